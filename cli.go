@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// runCommand サブコマンドを実行する。GUIを起動する場合はfalseを返す
+func runCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "replay":
+		return true, runReplayCommand(args[1:])
+	case "loadgen":
+		return true, runLoadgenCommand(args[1:])
+	case "validate":
+		return true, runValidateCommand(args[1:])
+	case "macro":
+		return true, runMacroCommand(args[1:])
+	}
+	return false, nil
+}
+
+// runReplayCommand replayサブコマンド: セッションファイルをGUIなしで再生する
+func runReplayCommand(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	host := fs.String("host", "127.0.0.1", "destination host")
+	port := fs.Int("port", 7000, "destination port")
+	speed := fs.Float64("speed", 1.0, "playback speed multiplier")
+	loop := fs.Bool("loop", false, "repeat the session until interrupted")
+	start := fs.Duration("start", 0, "start offset from the beginning of the session (e.g. 10s)")
+	end := fs.Duration("end", 0, "end offset from the beginning of the session (0 = until the end)")
+	filter := fs.String("filter", "", "address filter (e.g. /test*)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-osc-checker replay [flags] <session.jsonl>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("specify one session file")
+	}
+
+	records, err := LoadSession(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	replayer, err := NewReplayer(records, ReplayOptions{
+		Host:   *host,
+		Port:   *port,
+		Speed:  *speed,
+		Loop:   *loop,
+		Start:  *start,
+		End:    *end,
+		Filter: *filter,
+	})
+	if err != nil {
+		return err
+	}
+	replayer.OnProgress = func(sent, total, loop int) {
+		fmt.Printf("\r[loop %d] %d / %d", loop, sent, total)
+	}
+
+	// Ctrl+Cで停止
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		replayer.Stop()
+	}()
+
+	fmt.Printf("Replaying %s → %s:%d (%d messages, x%g)\n", fs.Arg(0), *host, *port, replayer.Total(), *speed)
+	err = replayer.Run()
+	fmt.Println()
+	return err
+}
+
+// argumentFlags "type:value" 形式で繰り返し指定できる引数フラグ
+type argumentFlags []OSCArgument
+
+// String 現在の値を文字列で返す
+func (f *argumentFlags) String() string {
+	return formatArguments(*f)
+}
+
+// Set "type:value" を解析して追加する
+func (f *argumentFlags) Set(value string) error {
+	typ, val, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("arguments must look like type:value: %s", value)
+	}
+	*f = append(*f, OSCArgument{Type: typ, Value: val})
+	return nil
+}
+
+// loadCommandConfig サブコマンド用に設定ファイルを読み込む（空ならsettings.yamlの指定に従う）
+func loadCommandConfig(filename string) (*AppConfig, error) {
+	settings, err := resolveSettings("", filename)
+	if err != nil {
+		return nil, err
+	}
+	return LoadConfig(settings.ConfigFile)
+}
+
+// runLoadgenCommand loadgenサブコマンド: GUIなしで負荷試験を行う
+func runLoadgenCommand(args []string) error {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	configFile := fs.String("config", "", "config file (default: the one in settings/settings.yaml)")
+	targetName := fs.String("target", "", "sender target name from the config file")
+	host := fs.String("host", "", "destination host (overrides the target)")
+	port := fs.Int("port", 0, "destination port (overrides the target)")
+	address := fs.String("address", "", "OSC address (overrides the target)")
+	var arguments argumentFlags
+	fs.Var(&arguments, "arg", "argument as type:value, repeatable (overrides the target)")
+	rate := fs.Float64("rate", 1000, "messages per second")
+	burst := fs.Int("burst", 1, "messages sent back-to-back per tick")
+	duration := fs.Duration("duration", 10*time.Second, "test duration (0 = until interrupted)")
+	increment := fs.Bool("increment", false, "increment numeric arguments with every message")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-osc-checker loadgen [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := LoadOptions{
+		Rate:      *rate,
+		Burst:     *burst,
+		Duration:  *duration,
+		Increment: *increment,
+	}
+	if *targetName != "" {
+		config, err := loadCommandConfig(*configFile)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, target := range config.Sender.List {
+			if target.Name == *targetName {
+				opts.Host, opts.Port, opts.Address = target.Host, target.Port, target.Address
+				for _, argDef := range target.Arguments {
+					opts.Arguments = append(opts.Arguments, OSCArgument{Type: argDef.Type, Value: argDef.DefaultValue})
+				}
+				// テンプレートは開始時の値で固定する
+				if opts.Arguments, err = NewArgumentTemplates(nil).Evaluate(opts.Arguments); err != nil {
+					return err
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("target not found: %s", *targetName)
+		}
+	}
+	if *host != "" {
+		opts.Host = *host
+	}
+	if *port != 0 {
+		opts.Port = *port
+	}
+	if *address != "" {
+		opts.Address = *address
+	}
+	if len(arguments) > 0 {
+		opts.Arguments = arguments
+	}
+
+	generator, err := NewLoadGenerator(opts)
+	if err != nil {
+		return err
+	}
+	generator.OnUpdate = func(stats LoadStats) {
+		fmt.Printf("\r%s   ", formatLoadStats(stats))
+	}
+
+	// Ctrl+Cで停止
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		generator.Stop()
+	}()
+
+	fmt.Printf("Sending %s [%s] → %s:%d at %g msg/s (burst %d)\n", opts.Address, formatArguments(opts.Arguments), opts.Host, opts.Port, opts.Rate, opts.Burst)
+	stats, err := generator.Run()
+	fmt.Printf("\r%s   \n", formatLoadStats(stats))
+	return err
+}
+
+// runValidateCommand validateサブコマンド: 設定ファイルを検証して問題点を表示する
+func runValidateCommand(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-osc-checker validate [config.yaml ...]")
+		fmt.Fprintln(fs.Output(), "Checks config files (default: the one in settings/settings.yaml) and prints every problem as file:line:column.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		settings, err := resolveSettings("", "")
+		if err != nil {
+			return err
+		}
+		files = []string{settings.ConfigFile}
+	}
+
+	failed := 0
+	for _, filename := range files {
+		err := ValidateConfigFile(filename)
+		var configErr *ConfigError
+		switch {
+		case err == nil:
+			fmt.Printf("%s: OK\n", filename)
+		case errors.As(err, &configErr):
+			fmt.Println(configErr.Error())
+			fmt.Printf("%s: %d problem(s)\n", filename, len(configErr.Issues))
+			failed++
+		default:
+			fmt.Printf("%s: %v\n", filename, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d config file(s) have problems", failed)
+	}
+	return nil
+}
+
+// runMacroCommand macroサブコマンド: 設定ファイルのマクロをGUIなしで実行する
+func runMacroCommand(args []string) error {
+	fs := flag.NewFlagSet("macro", flag.ContinueOnError)
+	configFile := fs.String("config", "", "config file (default: the one in settings/settings.yaml)")
+	list := fs.Bool("list", false, "list the macros in the config file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-osc-checker macro [flags] <name>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := loadCommandConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, macro := range config.Sender.Macros {
+			fmt.Printf("%s (%d steps)\n", macro.Name, len(macro.Steps))
+		}
+		return nil
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("specify one macro name")
+	}
+
+	macro, ok := findMacro(config.Sender.Macros, fs.Arg(0))
+	if !ok {
+		return fmt.Errorf("macro not found: %s", fs.Arg(0))
+	}
+	runner, err := NewMacroRunner(macro, config.Sender.List)
+	if err != nil {
+		return err
+	}
+	steps := runner.Steps()
+	runner.OnResult = func(result MacroResult) {
+		status := "OK"
+		if result.Err != nil {
+			status = fmt.Sprintf("ERROR %v", result.Err)
+		}
+		fmt.Printf("%s [%d/%d] %s  %s\n", result.Time.Format("15:04:05.000"), result.Step+1, len(steps), steps[result.Step], status)
+	}
+
+	// Ctrl+Cで停止
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		runner.Stop()
+	}()
+
+	fmt.Printf("Running macro %s (%d steps)\n", macro.Name, len(steps))
+	return runner.Run()
+}
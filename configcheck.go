@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/gopher-lua/parse"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigIssue 設定ファイルの問題点（位置がわかれば行・列つき）
+type ConfigIssue struct {
+	File    string // includeしたファイルの問題ならそのファイル名（空=検証したファイル）
+	Line    int
+	Column  int
+	Path    string // 例: sender.list[0].port（YAMLの構文エラーなどでは空）
+	Message string
+}
+
+// String "行:列: パス: 内容" の形式にする
+func (i ConfigIssue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "%d:", i.Line)
+		if i.Column > 0 {
+			fmt.Fprintf(&b, "%d:", i.Column)
+		}
+		b.WriteString(" ")
+	}
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// ConfigError 設定ファイルの問題点の一覧
+type ConfigError struct {
+	Filename string
+	Issues   []ConfigIssue
+}
+
+// Error 問題点を1行に1つずつ並べる
+func (e *ConfigError) Error() string {
+	lines := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		filename := issue.File
+		if filename == "" {
+			filename = e.Filename
+		}
+		lines = append(lines, filename+":"+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateConfigFile 設定ファイルを検証する。問題があれば*ConfigErrorを返す
+func ValidateConfigFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return ValidateConfigData(filename, data)
+}
+
+// yamlErrorLine yaml.v3のエラーメッセージから行番号を取り出す
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// ValidateConfigData 設定ファイルの内容を検証する（includeしたファイルも含む）
+// 構文・型・未知のキー・変数のエラーと、値の内容の問題をすべてまとめて返す
+func ValidateConfigData(filename string, data []byte) error {
+	doc := loadConfigDocument(filename, data)
+	result := &ConfigError{Filename: filename}
+	result.Issues = append(result.Issues, doc.issues...)
+
+	// 型と未知のキーはファイルごとに確認する（行番号がそのファイルのものになるように）
+	for _, source := range doc.sources {
+		var partial AppConfig
+		if err := source.root.Decode(&partial); err != nil {
+			var typeErr *yaml.TypeError
+			if errors.As(err, &typeErr) {
+				for _, msg := range typeErr.Errors {
+					issue := yamlErrorIssue(msg)
+					issue.File = source.filename
+					result.Issues = append(result.Issues, issue)
+				}
+			} else {
+				result.Issues = append(result.Issues, ConfigIssue{File: source.filename, Message: err.Error()})
+			}
+		}
+		checkYAMLFields(source.root, reflect.TypeOf(partial), func(node *yaml.Node, key string) {
+			result.Issues = append(result.Issues, ConfigIssue{
+				File:    source.filename,
+				Line:    node.Line,
+				Column:  node.Column,
+				Message: fmt.Sprintf("unknown key: %s", key),
+			})
+		})
+	}
+
+	// 値の内容はすべてのファイルを重ね合わせた設定で確認する
+	config := &AppConfig{}
+	doc.root.Decode(config) // 型のエラーは報告済み
+
+	v := &configValidator{filename: filename, root: doc.root, files: doc.files, reported: map[string]bool{}}
+	for _, issue := range result.Issues {
+		v.reported[issue.location(filename)] = true
+	}
+	v.validate(config)
+	result.Issues = append(result.Issues, v.issues...)
+
+	if len(result.Issues) == 0 {
+		return nil
+	}
+	return result
+}
+
+// location 問題のあるファイルと行（同じ行の問題を重ねて報告しないため）
+func (i ConfigIssue) location(filename string) string {
+	if i.File != "" {
+		filename = i.File
+	}
+	return fmt.Sprintf("%s:%d", filename, i.Line)
+}
+
+// yamlErrorIssue yaml.v3のエラーメッセージをConfigIssueにする
+func yamlErrorIssue(msg string) ConfigIssue {
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return ConfigIssue{Line: line, Message: m[2]}
+	}
+	return ConfigIssue{Message: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+// configValidator 設定の値を検証し、問題点をYAML上の位置と合わせて集める
+type configValidator struct {
+	filename string // スクリプトファイルの場所の基準
+	root     *yaml.Node
+	files    map[*yaml.Node]string // ノードの属するファイル（includeしたファイルの場合）
+	issues   []ConfigIssue
+	// reported 型のエラーを報告済みの行（読めなかった値について重ねて報告しない）
+	reported map[string]bool
+}
+
+// add 問題点を追加する。pathはキー（string）と要素番号（int）の並び
+func (v *configValidator) add(path []any, format string, args ...any) {
+	issue := ConfigIssue{Path: formatConfigPath(path), Message: fmt.Sprintf(format, args...)}
+	if node := findYAMLNode(v.root, path); node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+		issue.File = v.files[node]
+	}
+	if issue.Line > 0 && v.reported[issue.location("")] {
+		return
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// formatConfigPath パスを sender.list[0].port の形式にする
+func formatConfigPath(path []any) string {
+	var b strings.Builder
+	for _, elem := range path {
+		switch e := elem.(type) {
+		case string:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(e)
+		case int:
+			fmt.Fprintf(&b, "[%d]", e)
+		}
+	}
+	return b.String()
+}
+
+// findYAMLNode パスに対応するノードを返す。キーが書かれていなければ、たどれた一番深いノードを返す
+func findYAMLNode(node *yaml.Node, path []any) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	for _, elem := range path {
+		var next *yaml.Node
+		switch e := elem.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == e {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && e < len(node.Content) {
+				next = node.Content[e]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// configPath パスに要素を追加した新しいパスを返す
+func configPath(path []any, elems ...any) []any {
+	return append(append([]any(nil), path...), elems...)
+}
+
+// validate 設定全体を検証する
+func (v *configValidator) validate(config *AppConfig) {
+	names := map[string]int{}
+	hotkeys := map[Hotkey]int{}
+	for i, target := range config.Sender.List {
+		path := []any{"sender", "list", i}
+		if target.Name == "" {
+			v.add(configPath(path, "name"), "name is required")
+		} else if first, ok := names[target.Name]; ok {
+			v.add(configPath(path, "name"), "name duplicates sender.list[%d]: %s", first, target.Name)
+		} else {
+			names[target.Name] = i
+		}
+		if target.Hotkey != "" && !strings.EqualFold(target.Hotkey, hotkeyNone) {
+			if h, err := parseHotkey(target.Hotkey); err != nil {
+				v.add(configPath(path, "hotkey"), "%v", err)
+			} else if first, ok := hotkeys[h]; ok {
+				v.add(configPath(path, "hotkey"), "hotkey duplicates sender.list[%d]: %s", first, h)
+			} else {
+				hotkeys[h] = i
+			}
+		}
+		v.validateTarget(path, target)
+	}
+
+	macroNames := map[string]int{}
+	for i, macro := range config.Sender.Macros {
+		path := []any{"sender", "macros", i}
+		if macro.Name == "" {
+			v.add(configPath(path, "name"), "name is required")
+		} else if first, ok := macroNames[macro.Name]; ok {
+			v.add(configPath(path, "name"), "name duplicates sender.macros[%d]: %s", first, macro.Name)
+		} else {
+			macroNames[macro.Name] = i
+		}
+		if len(macro.Steps) == 0 {
+			v.add(configPath(path, "steps"), "add at least one step")
+		}
+		for j, step := range macro.Steps {
+			if _, err := resolveMacroStep(step, config.Sender.List); err != nil {
+				v.add(configPath(path, "steps", j), "%v", err)
+			}
+		}
+	}
+
+	if port := config.Receiver.DefaultPort; port <= 0 || port > 65535 {
+		v.add([]any{"receiver", "default_port"}, "port must be between 1 and 65535: %d", port)
+	}
+	if config.Receiver.MaxLogEntries <= 0 {
+		v.add([]any{"receiver", "max_log_entries"}, "must be 1 or more: %d", config.Receiver.MaxLogEntries)
+	}
+	if port := config.Receiver.WebSocket.Port; port < 0 || port > 65535 {
+		v.add([]any{"receiver", "websocket", "port"}, "port must be between 1 and 65535: %d", port)
+	} else if port != 0 && port == config.Receiver.DefaultPort {
+		v.add([]any{"receiver", "websocket", "port"}, "same port as the receiver: %d", port)
+	}
+	for i, origin := range config.Receiver.WebSocket.AllowedOrigins {
+		if u, err := url.Parse(origin); origin != "*" && (err != nil || u.Scheme == "" || u.Host == "") {
+			v.add([]any{"receiver", "websocket", "allowed_origins", i}, "origin must look like http://host:port: %s", origin)
+		}
+	}
+
+	routeNames := map[string]int{}
+	for i, route := range config.Router.Routes {
+		path := []any{"router", "routes", i}
+		if first, ok := routeNames[route.Name]; ok && route.Name != "" {
+			v.add(configPath(path, "name"), "name duplicates router.routes[%d]: %s", first, route.Name)
+		} else {
+			routeNames[route.Name] = i
+		}
+		v.validateRoute(path, route, config.Sender.List)
+	}
+
+	scriptNames := map[string]int{}
+	for i, script := range config.Scripts {
+		path := []any{"scripts", i}
+		if script.Name == "" {
+			v.add(configPath(path, "name"), "name is required")
+		} else if first, ok := scriptNames[script.Name]; ok {
+			v.add(configPath(path, "name"), "name duplicates scripts[%d]: %s", first, script.Name)
+		} else {
+			scriptNames[script.Name] = i
+		}
+		if script.File == "" {
+			v.add(configPath(path, "file"), "file is required")
+			continue
+		}
+		v.validateScript(configPath(path, "file"), scriptPath(v.filename, script.File))
+	}
+}
+
+// validateScript スクリプトファイルの構文を確認する。構文エラーはスクリプトファイルの行・列で報告する
+func (v *configValidator) validateScript(path []any, filename string) {
+	_, err := compileScript(filename)
+	var syntaxErr *parse.Error
+	switch {
+	case err == nil:
+	case errors.As(err, &syntaxErr):
+		issue := ConfigIssue{File: filename, Message: syntaxErr.Message}
+		if syntaxErr.Pos.Line == parse.EOF {
+			issue.Message += " (at end of file)"
+		} else {
+			issue.Line, issue.Column = syntaxErr.Pos.Line, syntaxErr.Pos.Column
+			issue.Message += fmt.Sprintf(" (near %s)", syntaxErr.Token)
+		}
+		v.issues = append(v.issues, issue)
+	default:
+		v.add(path, "%v", err)
+	}
+}
+
+// validateTarget 送信先を検証する
+func (v *configValidator) validateTarget(path []any, target SenderTarget) {
+	if target.Host == "" {
+		v.add(configPath(path, "host"), "host is required")
+	}
+	if target.Port <= 0 || target.Port > 65535 {
+		v.add(configPath(path, "port"), "port must be between 1 and 65535: %d", target.Port)
+	}
+	if !strings.HasPrefix(target.Address, "/") {
+		v.add(configPath(path, "address"), "OSC address must start with /: %q", target.Address)
+	}
+	if seq := target.Sequence; seq != "" && seq != "argument" && seq != "address" && !strings.HasPrefix(seq, "/") {
+		v.add(configPath(path, "sequence"), "sequence must be argument, address or an address starting with /: %s", seq)
+	}
+	if target.RepeatInterval < 0 {
+		v.add(configPath(path, "repeat_interval"), "must be 0 or more: %d", target.RepeatInterval)
+	}
+	if target.SendRate < 0 {
+		v.add(configPath(path, "send_rate"), "must be 0 or more: %g", target.SendRate)
+	}
+
+	for j, arg := range target.Arguments {
+		argPath := configPath(path, "arguments", j)
+		if (OSCArgument{Type: arg.Type}).TypeTag() == "?" {
+			v.add(configPath(argPath, "type"), "unsupported argument type: %q (int, float, string, bool, int64, double, blob, timetag, nil)", arg.Type)
+			continue
+		}
+		if hasTemplate(arg.DefaultValue) {
+			if err := checkTemplate(arg.DefaultValue, arg.Type); err != nil {
+				v.add(configPath(argPath, "default_value"), "%v", err)
+			}
+		} else if arg.DefaultValue != "" {
+			if _, err := (OSCArgument{Type: arg.Type, Value: arg.DefaultValue}).OSCValue(); err != nil {
+				v.add(configPath(argPath, "default_value"), "%v", err)
+			}
+		}
+
+		var next *SenderArgument
+		if j+1 < len(target.Arguments) {
+			next = &target.Arguments[j+1]
+		}
+		if err := arg.validateRules(); err != nil {
+			v.add(argPath, "%v", err)
+		} else if err := arg.validateControl(next); err != nil {
+			v.add(configPath(argPath, "widget"), "%v", err)
+		}
+		if arg.Generator != nil {
+			if !isNumericArgumentType(arg.Type) {
+				v.add(configPath(argPath, "generator"), "generator can only be set on numeric arguments: %s", arg.Type)
+			} else if err := arg.Generator.validate(); err != nil {
+				v.add(configPath(argPath, "generator"), "%v", err)
+			}
+		}
+	}
+}
+
+// validateRoute 転送ルールを検証する
+func (v *configValidator) validateRoute(path []any, route RouteConfig, targets []SenderTarget) {
+	if _, err := compileOSCPattern(route.Pattern); err != nil {
+		v.add(configPath(path, "pattern"), "%v", err)
+	} else if _, err := compileRewriteRules(route.Rules, route.Pattern); err != nil {
+		v.add(configPath(path, "rules"), "%v", err)
+	}
+
+	for k, dest := range route.Destinations {
+		destPath := configPath(path, "destinations", k)
+		if dest.Target != "" {
+			found := false
+			for _, target := range targets {
+				if target.Name == dest.Target {
+					found = true
+					break
+				}
+			}
+			if !found {
+				v.add(configPath(destPath, "target"), "target not found: %s", dest.Target)
+			}
+			continue
+		}
+		if dest.Host == "" {
+			v.add(configPath(destPath, "host"), "host or target is required")
+		}
+		if dest.Port <= 0 || dest.Port > 65535 {
+			v.add(configPath(destPath, "port"), "port must be between 1 and 65535: %d", dest.Port)
+		}
+	}
+}
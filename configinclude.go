@@ -0,0 +1,462 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 設定ファイルの先頭に書ける指示（AppConfigには含めない）
+const (
+	configIncludeKey   = "include"   // 読み込む別の設定ファイル（文字列またはリスト）
+	configVariablesKey = "variables" // ${NAME} で参照できる変数
+)
+
+// configVariablePattern 変数の参照 ${NAME}（ルールの $1・${2} とは区別する）
+var configVariablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// configSource 設定ファイル1つ分の内容
+type configSource struct {
+	filename string
+	root     *yaml.Node // include・variablesを取り除いたマッピング
+}
+
+// configDocument includeしたファイルを重ね合わせ、変数を置き換えた設定
+type configDocument struct {
+	sources   []configSource // includeしたファイルが先、指定したファイルが最後
+	variables map[string]string
+	root      *yaml.Node // すべてのファイルを重ね合わせたマッピング
+	base      *yaml.Node // includeしたファイルだけを重ね合わせたマッピング（なければnil）
+	files     map[*yaml.Node]string
+	issues    []ConfigIssue
+}
+
+// loadConfigDocument 設定ファイルを読み込み、includeと変数を展開する
+// 問題があってもわかる範囲で展開し、問題点はissuesに記録する
+func loadConfigDocument(filename string, data []byte) *configDocument {
+	doc := &configDocument{
+		variables: map[string]string{},
+		files:     map[*yaml.Node]string{},
+	}
+	doc.load(filename, data, nil)
+
+	for _, source := range doc.sources {
+		doc.interpolate(source)
+		doc.register(source.root, source.filename)
+	}
+
+	doc.root = &yaml.Node{Kind: yaml.MappingNode}
+	for i, source := range doc.sources {
+		if i == len(doc.sources)-1 && i > 0 {
+			doc.base = doc.root
+		}
+		doc.root = doc.overlay(doc.root, source.root)
+	}
+	return doc
+}
+
+// configSnapshot 設定ファイルとincludeしたファイルの内容をまとめて返す（変更の検出用）
+// あわせて監視するファイルの一覧も返す
+func configSnapshot(filename string) ([]byte, []string) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, []string{filename}
+	}
+	snapshot := append([]byte(nil), data...)
+	files := []string{filename}
+	for _, source := range loadConfigDocument(filename, data).sources {
+		if source.filename == filename {
+			continue
+		}
+		included, _ := os.ReadFile(source.filename)
+		snapshot = append(append(snapshot, 0), included...)
+		files = append(files, source.filename)
+	}
+	return snapshot, files
+}
+
+// addIssue ファイル上の位置つきで問題点を記録する
+func (d *configDocument) addIssue(filename string, node *yaml.Node, format string, args ...any) {
+	issue := ConfigIssue{File: filename, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	d.issues = append(d.issues, issue)
+}
+
+// load 1つのファイルを読み込み、includeしたファイルを先に読み込む
+// stackは読み込み中のファイル（循環の検出用）
+func (d *configDocument) load(filename string, data []byte, stack []string) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		issue := yamlErrorIssue(err.Error())
+		issue.File = filename
+		d.issues = append(d.issues, issue)
+		return
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Line: 1, Column: 1}
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		root = node.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		d.addIssue(filename, root, "the top level of a config file must be a mapping")
+		return
+	}
+
+	stack = append(stack, filename)
+	var includes []*yaml.Node
+	variables := map[string]string{}
+	content := make([]*yaml.Node, 0, len(root.Content))
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case configIncludeKey:
+			switch value.Kind {
+			case yaml.ScalarNode:
+				includes = append(includes, value)
+			case yaml.SequenceNode:
+				includes = append(includes, value.Content...)
+			default:
+				d.addIssue(filename, value, "include must be a file name or a list of file names")
+			}
+		case configVariablesKey:
+			d.loadVariables(filename, value, variables)
+		default:
+			content = append(content, key, value)
+		}
+	}
+	root.Content = content
+
+	// 変数はincludeしたファイルより、includeを書いたファイルのものを優先する
+	for _, include := range includes {
+		d.include(filename, include, stack)
+	}
+	for name, value := range variables {
+		d.variables[name] = value
+	}
+	d.sources = append(d.sources, configSource{filename: filename, root: root})
+}
+
+// include includeに書かれたファイルを読み込む（相対パスは書いたファイルのディレクトリから）
+func (d *configDocument) include(filename string, node *yaml.Node, stack []string) {
+	if node.Kind != yaml.ScalarNode || node.Value == "" {
+		d.addIssue(filename, node, "include must be a file name")
+		return
+	}
+	path := node.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(filename), path)
+	}
+	for _, loading := range stack {
+		if sameFile(loading, path) {
+			d.addIssue(filename, node, "circular include: %s", node.Value)
+			return
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		d.addIssue(filename, node, "cannot read the included file: %v", err)
+		return
+	}
+	d.load(path, data, stack)
+}
+
+// loadVariables variablesに書かれた変数をvariablesに読み込む
+func (d *configDocument) loadVariables(filename string, node *yaml.Node, variables map[string]string) {
+	if node.Kind != yaml.MappingNode {
+		d.addIssue(filename, node, "variables must be a mapping of name: value")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if !configVariablePattern.MatchString("${" + key.Value + "}") {
+			d.addIssue(filename, key, "invalid character in variable name: %s", key.Value)
+			continue
+		}
+		if value.Kind != yaml.ScalarNode {
+			d.addIssue(filename, value, "variable %s must be a string or a number", key.Value)
+			continue
+		}
+		variables[key.Value] = value.Value
+	}
+}
+
+// lookupVariable 変数の値を返す（variablesになければ環境変数）
+func (d *configDocument) lookupVariable(name string) (string, bool) {
+	if value, ok := d.variables[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// expandConfigVariables 文字列中の ${NAME} を置き換える。未定義の変数名も返す
+func expandConfigVariables(text string, lookup func(string) (string, bool)) (string, []string) {
+	var undefined []string
+	expanded := configVariablePattern.ReplaceAllStringFunc(text, func(ref string) string {
+		name := configVariablePattern.FindStringSubmatch(ref)[1]
+		value, ok := lookup(name)
+		if !ok {
+			undefined = append(undefined, name)
+			return ref
+		}
+		return value
+	})
+	return expanded, undefined
+}
+
+// interpolate 変数を使える項目（送信先のhost・port・address・引数の初期値、マクロのステップ、転送先のhost・port）の変数を置き換える
+func (d *configDocument) interpolate(source configSource) {
+	var fields []*yaml.Node
+	for _, target := range yamlSequenceItems(yamlMappingValue(yamlMappingValue(source.root, "sender"), "list")) {
+		fields = append(fields, yamlMappingValue(target, "host"), yamlMappingValue(target, "port"), yamlMappingValue(target, "address"))
+		for _, arg := range yamlSequenceItems(yamlMappingValue(target, "arguments")) {
+			fields = append(fields, yamlMappingValue(arg, "default_value"))
+		}
+	}
+	for _, macro := range yamlSequenceItems(yamlMappingValue(yamlMappingValue(source.root, "sender"), "macros")) {
+		for _, step := range yamlSequenceItems(yamlMappingValue(macro, "steps")) {
+			fields = append(fields, yamlMappingValue(step, "host"), yamlMappingValue(step, "port"), yamlMappingValue(step, "address"))
+			for _, arg := range yamlSequenceItems(yamlMappingValue(step, "arguments")) {
+				fields = append(fields, yamlMappingValue(arg, "value"))
+			}
+		}
+	}
+	for _, route := range yamlSequenceItems(yamlMappingValue(yamlMappingValue(source.root, "router"), "routes")) {
+		for _, dest := range yamlSequenceItems(yamlMappingValue(route, "destinations")) {
+			fields = append(fields, yamlMappingValue(dest, "host"), yamlMappingValue(dest, "port"))
+		}
+	}
+
+	for _, node := range fields {
+		if node == nil || node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, "${") {
+			continue
+		}
+		expanded, undefined := expandConfigVariables(node.Value, d.lookupVariable)
+		for _, name := range undefined {
+			d.addIssue(source.filename, node, "undefined variable %s (not in variables or the environment)", name)
+		}
+		if len(undefined) > 0 {
+			continue
+		}
+		// 置き換えた値で型を判定し直す（port: ${FOH_PORT} を数値として読めるように）
+		node.Value = expanded
+		node.Tag = ""
+		node.Style = 0
+	}
+}
+
+// register ノードとファイル名を対応づける（エラー位置の表示用）
+func (d *configDocument) register(node *yaml.Node, filename string) {
+	d.files[node] = filename
+	for _, child := range node.Content {
+		d.register(child, filename)
+	}
+}
+
+// overlay dstにsrcを重ねた新しいノードを返す
+// マッピングはキーごと、nameを持つ要素のリストはnameごとに重ね、それ以外はsrcで置き換える
+func (d *configDocument) overlay(dst, src *yaml.Node) *yaml.Node {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		merged := *src
+		merged.Content = append([]*yaml.Node(nil), dst.Content...)
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			found := false
+			for j := 0; j+1 < len(merged.Content); j += 2 {
+				if merged.Content[j].Value == key.Value {
+					merged.Content[j+1] = d.overlay(merged.Content[j+1], value)
+					found = true
+					break
+				}
+			}
+			if !found {
+				merged.Content = append(merged.Content, key, value)
+			}
+		}
+		d.files[&merged] = d.files[src]
+		return &merged
+
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode && isNamedYAMLSequence(src):
+		merged := *src
+		merged.Content = append([]*yaml.Node(nil), dst.Content...)
+		for _, item := range src.Content {
+			name, _ := yamlMappingName(item)
+			found := false
+			for j, existing := range merged.Content {
+				if existingName, ok := yamlMappingName(existing); ok && existingName == name {
+					merged.Content[j] = d.overlay(existing, item)
+					found = true
+					break
+				}
+			}
+			if !found {
+				merged.Content = append(merged.Content, item)
+			}
+		}
+		d.files[&merged] = d.files[src]
+		return &merged
+	}
+	return src
+}
+
+// isNamedYAMLSequence すべての要素がnameを持つマッピングのリストかどうか
+func isNamedYAMLSequence(node *yaml.Node) bool {
+	for _, item := range node.Content {
+		if _, ok := yamlMappingName(item); !ok {
+			return false
+		}
+	}
+	return len(node.Content) > 0
+}
+
+// yamlMappingValue マッピングのキーに対応する値を返す（なければnil）
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlSequenceItems リストの要素を返す（リストでなければnil）
+func yamlSequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// checkYAMLFields 構造体にないキー（書き間違い）を探す
+func checkYAMLFields(node *yaml.Node, t reflect.Type, report func(node *yaml.Node, key string)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fields[name] = field.Type
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				report(key, key.Value)
+				continue
+			}
+			checkYAMLFields(node.Content[i+1], fieldType, report)
+		}
+	case reflect.Slice, reflect.Array:
+		for _, item := range yamlSequenceItems(node) {
+			checkYAMLFields(item, t.Elem(), report)
+		}
+	}
+}
+
+// omitIncludedYAML includeしたファイルと同じ値で、保存先のファイルに書かれていない部分をupdatedから取り除く
+// （保存時にincludeした送信先などを保存先のファイルへ書き写さないため）
+func omitIncludedYAML(updated, included, original *yaml.Node) {
+	switch {
+	case updated.Kind == yaml.MappingNode && included.Kind == yaml.MappingNode:
+		content := make([]*yaml.Node, 0, len(updated.Content))
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			key, value := updated.Content[i], updated.Content[i+1]
+			inc := yamlMappingValue(included, key.Value)
+			orig := yamlMappingValue(original, key.Value)
+			if inc != nil && orig == nil && key.Value != "name" && equalYAMLValue(value, inc) {
+				continue
+			}
+			// どちらのファイルにもないゼロ値（arguments: [] など）は読み直しても同じなので書かない
+			if inc == nil && orig == nil && isEmptyYAMLValue(value) {
+				continue
+			}
+			if inc != nil {
+				omitIncludedYAML(value, inc, orig)
+				// すべてincludeしたものと同じだったマッピングはキーごと書かない
+				if orig == nil && value.Kind == yaml.MappingNode && len(value.Content) == 0 {
+					continue
+				}
+			}
+			content = append(content, key, value)
+		}
+		updated.Content = content
+
+	case updated.Kind == yaml.SequenceNode && included.Kind == yaml.SequenceNode && isNamedYAMLSequence(updated):
+		content := make([]*yaml.Node, 0, len(updated.Content))
+		for _, item := range updated.Content {
+			name, _ := yamlMappingName(item)
+			inc := findNamedYAMLItem(included, name)
+			orig := findNamedYAMLItem(original, name)
+			if inc != nil && orig == nil && equalYAMLValue(item, inc) {
+				continue
+			}
+			if inc != nil {
+				omitIncludedYAML(item, inc, orig)
+				// nameしか残らなければincludeしたものから変わっていない
+				if orig == nil && len(item.Content) == 2 {
+					continue
+				}
+			}
+			content = append(content, item)
+		}
+		updated.Content = content
+	}
+}
+
+// isEmptyYAMLValue ゼロ値のスカラー、空のリスト、ゼロ値だけのマッピングかどうか
+func isEmptyYAMLValue(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return isZeroYAMLScalar(node)
+	case yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if !isEmptyYAMLValue(node.Content[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// findNamedYAMLItem リストからnameが一致する要素を探す
+func findNamedYAMLItem(node *yaml.Node, name string) *yaml.Node {
+	for _, item := range yamlSequenceItems(node) {
+		if itemName, ok := yamlMappingName(item); ok && itemName == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// equalYAMLValue 2つのノードが同じ値を表しているかどうか
+func equalYAMLValue(a, b *yaml.Node) bool {
+	var va, vb any
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
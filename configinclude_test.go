@@ -0,0 +1,265 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigFiles 一時ディレクトリに設定ファイルを書き、ディレクトリを返す
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// loadTestDocument 設定ファイルを読み込んで展開する
+func loadTestDocument(t *testing.T, filename string) *configDocument {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loadConfigDocument(filename, data)
+}
+
+// targetsByName 送信先をnameで引けるようにする
+func targetsByName(targets []SenderTarget) map[string]SenderTarget {
+	byName := map[string]SenderTarget{}
+	for _, target := range targets {
+		byName[target.Name] = target
+	}
+	return byName
+}
+
+func TestConfigIncludeCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"self", map[string]string{
+			"main.yaml": "include: main.yaml\n",
+		}},
+		{"two files", map[string]string{
+			"main.yaml":  "include: other.yaml\n",
+			"other.yaml": "include: main.yaml\n",
+		}},
+		{"three files", map[string]string{
+			"main.yaml": "include: [a.yaml]\n",
+			"a.yaml":    "include: b.yaml\n",
+			"b.yaml":    "include: a.yaml\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+			doc := loadTestDocument(t, filepath.Join(dir, "main.yaml"))
+			found := false
+			for _, issue := range doc.issues {
+				found = found || strings.Contains(issue.Message, "circular include")
+			}
+			if !found {
+				t.Errorf("issues = %v, want an include cycle", doc.issues)
+			}
+		})
+	}
+}
+
+func TestConfigIncludeDiamond(t *testing.T) {
+	// main → left, right → common の菱形。commonは2回読まれても循環ではない
+	dir := writeConfigFiles(t, map[string]string{
+		"common.yaml": `
+receiver:
+  default_port: 7000
+sender:
+  list:
+    - name: "Shared"
+      host: "127.0.0.1"
+      port: 9000
+      address: "/shared"
+`,
+		"left.yaml": `
+include: common.yaml
+sender:
+  list:
+    - name: "Left"
+      host: "10.0.0.1"
+      port: 9001
+      address: "/left"
+`,
+		"right.yaml": `
+include: common.yaml
+sender:
+  list:
+    - name: "Shared"
+      port: 9100
+`,
+		"main.yaml": `
+include:
+  - left.yaml
+  - right.yaml
+receiver:
+  max_log_entries: 50
+`,
+	})
+
+	filename := filepath.Join(dir, "main.yaml")
+	if doc := loadTestDocument(t, filename); len(doc.issues) > 0 {
+		t.Fatalf("issues = %v", doc.issues)
+	}
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Sender.List) != 2 {
+		t.Fatalf("targets = %+v, want Shared and Left once each", config.Sender.List)
+	}
+	targets := targetsByName(config.Sender.List)
+	if shared := targets["Shared"]; shared.Port != 9100 || shared.Host != "127.0.0.1" || shared.Address != "/shared" {
+		t.Errorf("Shared = %+v, want port from right.yaml and the rest from common.yaml", shared)
+	}
+	if left := targets["Left"]; left.Port != 9001 {
+		t.Errorf("Left = %+v", left)
+	}
+	if config.Receiver.DefaultPort != 7000 || config.Receiver.MaxLogEntries != 50 {
+		t.Errorf("Receiver = %+v, want mappings merged key by key", config.Receiver)
+	}
+}
+
+func TestConfigVariables(t *testing.T) {
+	t.Setenv("FOH_IP", "10.0.0.3")
+	t.Setenv("ENV_ONLY", "10.0.0.4")
+	dir := writeConfigFiles(t, map[string]string{
+		"base.yaml": `
+variables:
+  FOH_IP: "10.0.0.2"
+  BASE_ONLY: "10.0.0.5"
+  FOH_PORT: 9000
+`,
+		"main.yaml": `
+include: base.yaml
+variables:
+  FOH_IP: "10.0.0.1"
+sender:
+  list:
+    - {name: "Main", host: "${FOH_IP}", port: "${FOH_PORT}", address: "/a"}
+    - {name: "Base", host: "${BASE_ONLY}", port: 1, address: "/b"}
+    - {name: "Env", host: "${ENV_ONLY}", port: 1, address: "/c"}
+    - {name: "Rule", host: "h", port: 1, address: "/d/$1"}
+`,
+	})
+
+	config, err := LoadConfig(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := targetsByName(config.Sender.List)
+	tests := []struct {
+		target string
+		got    string
+		want   string
+	}{
+		{"Main", targets["Main"].Host, "10.0.0.1"}, // includeを書いたファイルが優先
+		{"Base", targets["Base"].Host, "10.0.0.5"}, // includeしたファイルの変数
+		{"Env", targets["Env"].Host, "10.0.0.4"},   // どこにもなければ環境変数
+		{"Rule", targets["Rule"].Address, "/d/$1"}, // $1 は変数ではない
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.target, tt.got, tt.want)
+		}
+	}
+	if port := targets["Main"].Port; port != 9000 {
+		t.Errorf("Main port = %d, want 9000 read as a number", port)
+	}
+}
+
+func TestConfigVariablesUndefined(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"main.yaml": `
+sender:
+  list:
+    - {name: "Main", host: "${GO_OSC_CHECKER_TEST_UNDEFINED}", port: 1, address: "/a"}
+`,
+	})
+	doc := loadTestDocument(t, filepath.Join(dir, "main.yaml"))
+	if len(doc.issues) != 1 || !strings.Contains(doc.issues[0].Message, "GO_OSC_CHECKER_TEST_UNDEFINED") {
+		t.Errorf("issues = %v, want the undefined variable", doc.issues)
+	}
+}
+
+func TestSaveConfigKeepsIncludeAndVariables(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"base.yaml": `
+receiver:
+  default_port: 7000
+  max_log_entries: 100
+sender:
+  list:
+    - name: "Included"
+      host: "192.168.0.10"
+      port: 8000
+      address: "/included"
+`,
+		"main.yaml": `# stage config
+include: base.yaml
+variables:
+  FOH_IP: "10.0.0.1"
+sender:
+  list:
+    - name: "FOH"
+      host: "${FOH_IP}" # mixer
+      port: 9000
+      address: "/foh"
+`,
+	})
+	filename := filepath.Join(dir, "main.yaml")
+
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range config.Sender.List {
+		if config.Sender.List[i].Name == "FOH" {
+			config.Sender.List[i].Port = 9001
+		}
+	}
+	if err := SaveConfig(filename, config); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, want := range []string{"# stage config", "include: base.yaml", "variables:", `FOH_IP: "10.0.0.1"`, "${FOH_IP}", "# mixer", "9001"} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("saved file does not contain %q:\n%s", want, saved)
+		}
+	}
+	for _, unwanted := range []string{"Included", "192.168.0.10", "10.0.0.1\n", "default_port", "receiver"} {
+		if strings.Contains(saved, unwanted) {
+			t.Errorf("saved file contains %q copied from the included file or variables:\n%s", unwanted, saved)
+		}
+	}
+
+	// 保存したファイルを読み直しても同じ内容になる
+	reloaded, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := targetsByName(reloaded.Sender.List)
+	if foh := targets["FOH"]; foh.Host != "10.0.0.1" || foh.Port != 9001 {
+		t.Errorf("FOH = %+v after reload", foh)
+	}
+	if _, ok := targets["Included"]; !ok || len(reloaded.Sender.List) != 2 {
+		t.Errorf("targets = %+v after reload, want FOH and Included", reloaded.Sender.List)
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+	"gopkg.in/yaml.v3"
+)
+
+// SaveConfig 設定をYAMLファイルに保存する
+// 既存のファイルがあればコメント・キーの順序・空行・パーミッションを残したまま値を更新し、
+// 何も変わっていなければ書き込まない
+// include・variablesはそのまま残し、includeしたファイルと同じ内容や変数で書かれた値は書き換えない
+func SaveConfig(filename string, config *AppConfig) error {
+	var updated yaml.Node
+	if err := updated.Encode(config); err != nil {
+		return fmt.Errorf("cannot convert the config: %w", err)
+	}
+
+	// シンボリックリンクならリンク先のファイルを更新する
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = resolved
+	}
+
+	out := &updated
+	var unchanged []byte // 値を反映する前の既存のファイルを書き出した内容
+	data, err := os.ReadFile(filename)
+	switch {
+	case err == nil:
+		var original yaml.Node
+		if err := yaml.Unmarshal(data, &original); err != nil {
+			return fmt.Errorf("cannot parse the existing config file: %w", err)
+		}
+		if original.Kind == yaml.DocumentNode && len(original.Content) == 1 && original.Content[0].Kind == yaml.MappingNode {
+			if unchanged, err = encodeConfigYAML(&original); err != nil {
+				return err
+			}
+			root := original.Content[0]
+			doc := loadConfigDocument(filename, data)
+			if doc.base != nil {
+				omitIncludedYAML(&updated, doc.base, root)
+			}
+
+			// include・variablesは設定の値ではないので、値を反映した後に元の位置へ戻す
+			directives := takeConfigDirectives(root)
+			mergeYAMLNode(root, &updated, doc.lookupVariable)
+			root.Content = append(directives, root.Content...)
+			out = &original
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	encoded, err := encodeConfigYAML(out)
+	if err != nil {
+		return err
+	}
+	if unchanged != nil {
+		if bytes.Equal(encoded, unchanged) {
+			return nil
+		}
+		// YAMLとして書き出すと空行がなくなるので、元のファイルの空行を戻す
+		encoded = restoreBlankLines(data, encoded)
+	}
+
+	// 一時ファイルに書いてから置き換える（CreateTempは0600で作るので元のパーミッションに戻す）
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".config-*.yaml")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// encodeConfigYAML 設定ファイルの書式（インデント2）でYAMLにする
+func encodeConfigYAML(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, fmt.Errorf("cannot write the config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// maxBlankLineCells restoreBlankLinesで行を対応させる表の大きさの上限（超えたら空行は戻さない）
+const maxBlankLineCells = 4 << 20
+
+// restoreBlankLines 元のファイルと書き出した内容の行を対応させ、元のファイルにあった空行を同じ位置に戻す
+// 行の対応は最長共通部分列で求めるので、値を変えた行の前後の空行も残る
+func restoreBlankLines(original, encoded []byte) []byte {
+	orig := strings.Split(string(original), "\n")
+	out := strings.Split(string(encoded), "\n")
+	n, m := len(orig), len(out)
+	if (n+1)*(m+1) > maxBlankLineCells {
+		return encoded
+	}
+
+	// lcs[i][j] = orig[i:] と out[j:] の最長共通部分列の長さ
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if orig[i] == out[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	result := make([]string, 0, m+n)
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && orig[i] == out[j]:
+			result = append(result, out[j])
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			// 元のファイルにだけある行。空行なら戻す
+			if strings.TrimSpace(orig[i]) == "" {
+				result = append(result, "")
+			}
+			i++
+		default:
+			result = append(result, out[j])
+			j++
+		}
+	}
+	return []byte(strings.Join(result, "\n"))
+}
+
+// takeConfigDirectives マッピングからinclude・variablesのキーと値を取り出す
+func takeConfigDirectives(root *yaml.Node) []*yaml.Node {
+	var directives, content []*yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case configIncludeKey, configVariablesKey:
+			directives = append(directives, root.Content[i], root.Content[i+1])
+		default:
+			content = append(content, root.Content[i], root.Content[i+1])
+		}
+	}
+	root.Content = content
+	return directives
+}
+
+// mergeYAMLNode srcの値をdstに反映する（dstのコメント・順序・引用符の種類は残す）
+// dstが ${NAME} を使っていて、置き換えた結果がsrcと同じならそのまま残す
+func mergeYAMLNode(dst, src *yaml.Node, lookup func(string) (string, bool)) {
+	if dst.Kind != src.Kind {
+		replaceYAMLNode(dst, src)
+		return
+	}
+
+	switch dst.Kind {
+	case yaml.MappingNode:
+		mergeYAMLMapping(dst, src, lookup)
+	case yaml.SequenceNode:
+		mergeYAMLSequence(dst, src, lookup)
+	case yaml.ScalarNode:
+		if strings.Contains(dst.Value, "${") {
+			if expanded, undefined := expandConfigVariables(dst.Value, lookup); len(undefined) == 0 && expanded == src.Value {
+				return
+			}
+		}
+		if dst.Tag != src.Tag {
+			dst.Style = src.Style
+		}
+		dst.Tag = src.Tag
+		dst.Value = src.Value
+	default:
+		replaceYAMLNode(dst, src)
+	}
+}
+
+// replaceYAMLNode dstをsrcで置き換える（コメントだけ残す）
+func replaceYAMLNode(dst, src *yaml.Node) {
+	head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *src
+	dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+}
+
+// mergeYAMLMapping 既存のキーは順序を保って更新し、新しいキーは末尾に追加、なくなったキーは削除する
+// omitemptyで省略された空の値（min: 0、allowed_origins: [] など）は書かれていたとおりに残し、
+// 書かれていなかった空の値（window: {width: 0, ...} など）は読み込むと同じになるので追加しない
+func mergeYAMLMapping(dst, src *yaml.Node, lookup func(string) (string, bool)) {
+	srcValues := map[string]*yaml.Node{}
+	var srcKeys []*yaml.Node
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		srcValues[src.Content[i].Value] = src.Content[i+1]
+		srcKeys = append(srcKeys, src.Content[i])
+	}
+
+	merged := make([]*yaml.Node, 0, len(src.Content))
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key := dst.Content[i].Value
+		value, ok := srcValues[key]
+		if !ok {
+			if isEmptyYAMLValue(dst.Content[i+1]) {
+				merged = append(merged, dst.Content[i], dst.Content[i+1])
+			}
+			continue
+		}
+		mergeYAMLNode(dst.Content[i+1], value, lookup)
+		merged = append(merged, dst.Content[i], dst.Content[i+1])
+		seen[key] = true
+	}
+	for _, key := range srcKeys {
+		if value := srcValues[key.Value]; !seen[key.Value] && !isEmptyYAMLValue(value) {
+			pruneEmptyYAML(value)
+			merged = append(merged, key, value)
+		}
+	}
+	dst.Content = merged
+}
+
+// pruneEmptyYAML 新しく書き出すノードから空の値のキーを取り除く
+func pruneEmptyYAML(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		content := node.Content[:0]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if isEmptyYAMLValue(node.Content[i+1]) {
+				continue
+			}
+			pruneEmptyYAML(node.Content[i+1])
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+		node.Content = content
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			pruneEmptyYAML(item)
+		}
+	}
+}
+
+// mergeYAMLSequence 要素がnameを持つマッピングならname同士、それ以外は位置で対応させて更新する
+func mergeYAMLSequence(dst, src *yaml.Node, lookup func(string) (string, bool)) {
+	dstByName := map[string]*yaml.Node{}
+	for _, item := range dst.Content {
+		if name, ok := yamlMappingName(item); ok {
+			dstByName[name] = item
+		}
+	}
+
+	merged := make([]*yaml.Node, 0, len(src.Content))
+	for i, item := range src.Content {
+		var target *yaml.Node
+		if name, ok := yamlMappingName(item); ok {
+			target = dstByName[name]
+			delete(dstByName, name) // 同名が複数あっても1回だけ対応させる
+		} else if i < len(dst.Content) {
+			if _, named := yamlMappingName(dst.Content[i]); !named {
+				target = dst.Content[i]
+			}
+		}
+
+		if target == nil {
+			pruneEmptyYAML(item)
+			merged = append(merged, item)
+			continue
+		}
+		mergeYAMLNode(target, item, lookup)
+		merged = append(merged, target)
+	}
+	dst.Content = merged
+}
+
+// isZeroYAMLScalar ゼロ値のスカラーかどうか
+func isZeroYAMLScalar(node *yaml.Node) bool {
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+	switch node.Value {
+	case "", "0", "0.0", "false", "null", "~":
+		return true
+	}
+	return false
+}
+
+// yamlMappingName マッピングのnameキーの値を返す
+func yamlMappingName(node *yaml.Node) (string, bool) {
+	if node.Kind != yaml.MappingNode {
+		return "", false
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "name" && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value, true
+		}
+	}
+	return "", false
+}
+
+// senderSectionState 送信先カードの編集状態（未保存の変更があるか）
+type senderSectionState struct {
+	// current 入力中の内容をSenderTargetにする
+	current func() (SenderTarget, error)
+	// saved 最後に保存（または読み込み）した内容
+	saved      SenderTarget
+	dirtyLabel *widget.Label
+	// onChange 内容が変わったときに呼ばれる（メモリ上の設定への反映用）
+	onChange func()
+	// onRemove カードを削除するときの後始末（自動送信の停止など）
+	onRemove func()
+	// fire ホットキーで送信する（送信してカードを光らせる）
+	fire        func() bool
+	hotkeyLabel *widget.Label
+}
+
+// Target 入力中の内容を返す。入力が無効ならエラー
+func (s *senderSectionState) Target() (SenderTarget, error) {
+	return s.current()
+}
+
+// IsDirty 未保存の変更があるかどうか
+func (s *senderSectionState) IsDirty() bool {
+	target, _ := s.current()
+	return !reflect.DeepEqual(target, s.saved)
+}
+
+// Refresh 未保存の表示を更新する
+func (s *senderSectionState) Refresh() {
+	if s.current == nil {
+		return
+	}
+	if s.IsDirty() {
+		s.dirtyLabel.Show()
+	} else {
+		s.dirtyLabel.Hide()
+	}
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// MarkUnsaved まだ保存していない（新規作成した）カードにする
+func (s *senderSectionState) MarkUnsaved() {
+	s.saved = SenderTarget{}
+	s.Refresh()
+}
+
+// MarkSaved 現在の内容を保存済みにする
+func (s *senderSectionState) MarkSaved() {
+	s.saved, _ = s.current()
+	s.Refresh()
+}
+
+// renameTargetReferences 送信先の名前の変更に合わせて、転送ルールとマクロの参照を書き換える
+// 書き換えないと次に読み込んだときに送信先が見つからないエラーになる
+func renameTargetReferences(config *AppConfig, oldName, newName string) {
+	for i := range config.Router.Routes {
+		for j := range config.Router.Routes[i].Destinations {
+			if dest := &config.Router.Routes[i].Destinations[j]; dest.Target == oldName {
+				dest.Target = newName
+			}
+		}
+	}
+	for i := range config.Sender.Macros {
+		for j := range config.Sender.Macros[i].Steps {
+			if step := &config.Sender.Macros[i].Steps[j]; step.Target == oldName {
+				step.Target = newName
+			}
+		}
+	}
+}
+
+// senderSectionActions 送信先カードのメニューから呼ばれる処理
+type senderSectionActions struct {
+	Duplicate func(state *senderSectionState)
+	Delete    func(state *senderSectionState)
+	Move      func(state *senderSectionState, delta int)        // delta: -1=上へ, 1=下へ
+	Changed   func()                                            // 名前の変更など内容が変わった
+	NameInUse func(state *senderSectionState, name string) bool // 他のカードが使っている名前か
+	// Renamed 名前を変えたカードを参照している転送ルール・マクロを新しい名前に合わせる
+	Renamed func(oldName, newName string)
+	// ScriptsReferencing 名前を参照しているスクリプト（書き換えられないので名前を変える前に確認する）
+	ScriptsReferencing func(name string) []string
+	// HotkeyInUse 他のカードに割り当て済みのホットキーなら、そのカードの名前を返す
+	HotkeyInUse func(state *senderSectionState, h Hotkey) (string, bool)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSavedConfig 設定を書き込み、読み込んでからchangeを適用して保存した内容を返す
+func testSavedConfig(t *testing.T, content string, mode os.FileMode, change func(*AppConfig)) (string, os.FileMode) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filename, mode); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change != nil {
+		change(config)
+	}
+	if err := SaveConfig(filename, config); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), info.Mode().Perm()
+}
+
+const testSaveConfig = `# stage config
+
+sender:
+  list:
+    - name: "FOH"
+      host: "10.0.0.1"
+      port: 9000   # mixer
+      address: "/foh"
+
+    - name: "Lights"
+      host: "10.0.0.2"
+      port: 8000
+      address: "/lx"
+
+receiver:
+  default_port: 7000
+  websocket:
+    # keep this empty on purpose
+    allowed_origins: []
+`
+
+func TestSaveConfigUnchangedIsByteIdentical(t *testing.T) {
+	sample, err := os.ReadFile(filepath.Join("settings", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"sample":      string(sample),
+		"blank lines": testSaveConfig,
+		"odd format":  "sender:\n  list:\n  - name: A\n    host:   127.0.0.1\n    port: 9000\n    address: /a\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			saved, _ := testSavedConfig(t, content, 0644, nil)
+			if saved != content {
+				t.Errorf("saved file changed although nothing was edited:\n%s", saved)
+			}
+		})
+	}
+}
+
+func TestSaveConfigKeepsFormatting(t *testing.T) {
+	saved, _ := testSavedConfig(t, testSaveConfig, 0644, func(config *AppConfig) {
+		config.Sender.List[0].Port = 9001
+	})
+	// 値を変えた行以外は空行・コメント・空のリストも含めてそのまま
+	want := strings.Replace(testSaveConfig, "port: 9000   # mixer", "port: 9001 # mixer", 1)
+	if saved != want {
+		t.Errorf("saved file =\n%s\nwant\n%s", saved, want)
+	}
+}
+
+func TestSaveConfigKeepsPermissions(t *testing.T) {
+	for _, mode := range []os.FileMode{0644, 0640, 0600} {
+		_, got := testSavedConfig(t, testSaveConfig, mode, func(config *AppConfig) {
+			config.Sender.List[0].Port = 9001
+		})
+		if got != mode {
+			t.Errorf("mode after save = %v, want %v", got, mode)
+		}
+	}
+}
+
+func TestRenameTargetReferences(t *testing.T) {
+	config := &AppConfig{}
+	config.Router.Routes = []RouteConfig{{
+		Name:         "Forward",
+		Destinations: []RouteDestination{{Target: "Mixer"}, {Target: "Lights"}, {Host: "10.0.0.1", Port: 9000}},
+	}}
+	config.Sender.Macros = []MacroConfig{{
+		Name:  "Scene",
+		Steps: []MacroStep{{Target: "Mixer"}, {Delay: 100}, {Target: "Lights"}},
+	}}
+
+	renameTargetReferences(config, "Mixer", "Desk")
+
+	destinations := config.Router.Routes[0].Destinations
+	if destinations[0].Target != "Desk" || destinations[1].Target != "Lights" || destinations[2].Target != "" {
+		t.Errorf("route destinations = %+v", destinations)
+	}
+	steps := config.Sender.Macros[0].Steps
+	if steps[0].Target != "Desk" || steps[1].Target != "" || steps[2].Target != "Lights" {
+		t.Errorf("macro steps = %+v", steps)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloadDelay 保存が続けて起きた場合にまとめて読み込むまでの待ち時間
+const configReloadDelay = 300 * time.Millisecond
+
+// ConfigWatcher 設定ファイル（とincludeしたファイル）の変更を監視する
+// エディタの保存方法（置き換え・名前変更）に関係なく検出できるよう、ファイルのあるディレクトリを監視する
+type ConfigWatcher struct {
+	watcher  *fsnotify.Watcher
+	onChange func(filename string)
+
+	mu    sync.Mutex
+	dirs  map[string]bool
+	files map[string]bool
+	timer *time.Timer
+}
+
+// NewConfigWatcher 設定ファイルの監視を開始する。変更があるとonChangeを呼ぶ（監視用ゴルーチンから）
+func NewConfigWatcher(filenames []string, onChange func(filename string)) (*ConfigWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &ConfigWatcher{watcher: watcher, onChange: onChange, dirs: map[string]bool{}}
+	if err := w.SetFiles(filenames); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// SetFiles 監視するファイルを変更する
+func (w *ConfigWatcher) SetFiles(filenames []string) error {
+	files := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, filename := range filenames {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return err
+		}
+		files[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for dir := range dirs {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			return fmt.Errorf("cannot watch %s: %w", dir, err)
+		}
+	}
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			w.watcher.Remove(dir)
+		}
+	}
+	w.dirs = dirs
+	w.files = files
+	return nil
+}
+
+// run 変更イベントを待ち、対象のファイルならまとめて通知する
+func (w *ConfigWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+
+			w.mu.Lock()
+			if filename := filepath.Clean(event.Name); w.files[filename] {
+				if w.timer != nil {
+					w.timer.Stop()
+				}
+				w.timer = time.AfterFunc(configReloadDelay, func() {
+					w.onChange(filename)
+				})
+			}
+			w.mu.Unlock()
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("設定ファイルの監視エラー: %v", err)
+		}
+	}
+}
+
+// Close 監視を終了する
+func (w *ConfigWatcher) Close() error {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	return w.watcher.Close()
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 引数の操作用ウィジェットの種類
+const (
+	controlSlider   = "slider"
+	controlKnob     = "knob" // 上下にドラッグして回すノブ
+	controlToggle   = "toggle"
+	controlDropdown = "dropdown"
+	controlXY       = "xy" // この引数をX、次の引数をYとして操作する
+)
+
+// validateControl 引数の操作用ウィジェットの設定を検証する
+// nextはXYパッドでYに使う次の引数（なければnil）。minとmaxはvalidateRulesで検証する
+func (a SenderArgument) validateControl(next *SenderArgument) error {
+	switch a.Widget {
+	case "":
+		return nil
+	case controlSlider, controlKnob:
+		if !isNumericArgumentType(a.Type) {
+			return fmt.Errorf("%s can only be used with a numeric argument, not %s", a.Widget, a.Type)
+		}
+	case controlToggle:
+		if a.Type != "bool" && !isNumericArgumentType(a.Type) {
+			return fmt.Errorf("toggle can only be used with a bool or numeric argument, not %s", a.Type)
+		}
+	case controlDropdown:
+		if len(a.Options) == 0 {
+			return errors.New("dropdown needs options")
+		}
+	case controlXY:
+		if !isNumericArgumentType(a.Type) {
+			return fmt.Errorf("xy can only be used with a numeric argument, not %s", a.Type)
+		}
+		if next == nil || !isNumericArgumentType(next.Type) {
+			return errors.New("xy needs a numeric argument after it for Y")
+		}
+	default:
+		return fmt.Errorf("unsupported widget: %s (slider, knob, toggle, dropdown, xy)", a.Widget)
+	}
+	if a.Step < 0 {
+		return fmt.Errorf("step must be 0 or more: %g", a.Step)
+	}
+	return nil
+}
+
+// controlRange 操作範囲を返す（未指定なら0〜1）
+func (a SenderArgument) controlRange() (float64, float64) {
+	if a.Min == a.Max {
+		return 0, 1
+	}
+	return a.Min, a.Max
+}
+
+// quantize 値をstep単位に丸める
+func (a SenderArgument) quantize(value float64) float64 {
+	low, high := a.controlRange()
+	if a.Step > 0 {
+		value = low + math.Round((value-low)/a.Step)*a.Step
+	}
+	return math.Max(low, math.Min(high, value))
+}
+
+// formatControlValue 操作した値を引数タイプに合わせた文字列にする
+func formatControlValue(typ string, value float64) string {
+	switch typ {
+	case "int", "int64":
+		return strconv.FormatInt(int64(math.Round(value)), 10)
+	case "float":
+		return strconv.FormatFloat(value, 'g', 6, 64)
+	}
+	return strconv.FormatFloat(value, 'g', 12, 64)
+}
+
+// controlValue 引数の値を数値として読み取る（読めなければ範囲の下限）
+func (a SenderArgument) controlValue(text string) float64 {
+	low, _ := a.controlRange()
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return low
+	}
+	return value
+}
+
+// newArgumentControl 引数の操作用ウィジェットを作成する（XYパッドは除く）
+// 操作されるとonChangeに新しい値の文字列を渡す
+func newArgumentControl(def SenderArgument, typ, value string, onChange func(string)) fyne.CanvasObject {
+	switch def.Widget {
+	case controlKnob:
+		low, high := def.controlRange()
+		return NewKnob((def.controlValue(value)-low)/(high-low), func(v float64) {
+			onChange(formatControlValue(typ, def.quantize(low+v*(high-low))))
+		})
+
+	case controlSlider:
+		low, high := def.controlRange()
+		slider := widget.NewSlider(low, high)
+		slider.Step = def.Step
+		if slider.Step == 0 {
+			slider.Step = (high - low) / 1000
+			if typ == "int" || typ == "int64" {
+				slider.Step = 1
+			}
+		}
+		slider.SetValue(def.controlValue(value))
+		slider.OnChanged = func(v float64) {
+			onChange(formatControlValue(typ, def.quantize(v)))
+		}
+		return slider
+
+	case controlToggle:
+		check := widget.NewCheck("", nil)
+		if typ == "bool" {
+			checked, _ := strconv.ParseBool(value)
+			check.SetChecked(checked)
+		} else {
+			_, high := def.controlRange()
+			check.SetChecked(def.controlValue(value) == high)
+		}
+		check.OnChanged = func(checked bool) {
+			if typ == "bool" {
+				onChange(strconv.FormatBool(checked))
+				return
+			}
+			low, high := def.controlRange()
+			if checked {
+				onChange(formatControlValue(typ, high))
+			} else {
+				onChange(formatControlValue(typ, low))
+			}
+		}
+		return check
+
+	case controlDropdown:
+		sel := widget.NewSelect(def.Options, nil)
+		sel.SetSelected(value)
+		sel.OnChanged = onChange
+		return sel
+	}
+	return nil
+}
+
+// XYPad 2つの値を同時に操作するパッド（X・Yとも0〜1で保持する）
+type XYPad struct {
+	widget.BaseWidget
+
+	X, Y float64
+
+	// OnChanged ドラッグ・タップで位置が変わったときに呼ばれる
+	OnChanged func(x, y float64)
+}
+
+// NewXYPad XYパッドを作成する
+func NewXYPad(x, y float64, onChanged func(x, y float64)) *XYPad {
+	pad := &XYPad{
+		X:         math.Max(0, math.Min(1, x)),
+		Y:         math.Max(0, math.Min(1, y)),
+		OnChanged: onChanged,
+	}
+	pad.ExtendBaseWidget(pad)
+	return pad
+}
+
+// Tapped タップした位置に移動する
+func (p *XYPad) Tapped(ev *fyne.PointEvent) {
+	p.moveTo(ev.Position)
+}
+
+// Dragged ドラッグに追従する
+func (p *XYPad) Dragged(ev *fyne.DragEvent) {
+	p.moveTo(ev.Position)
+}
+
+// DragEnd ドラッグ終了（何もしない）
+func (p *XYPad) DragEnd() {
+}
+
+// moveTo パッド上の座標から値を更新する（上がYの最大値）
+func (p *XYPad) moveTo(pos fyne.Position) {
+	size := p.Size()
+	if size.Width <= 0 || size.Height <= 0 {
+		return
+	}
+	p.X = math.Max(0, math.Min(1, float64(pos.X/size.Width)))
+	p.Y = math.Max(0, math.Min(1, 1-float64(pos.Y/size.Height)))
+	p.Refresh()
+	if p.OnChanged != nil {
+		p.OnChanged(p.X, p.Y)
+	}
+}
+
+// CreateRenderer ウィジェットの描画を作成する
+func (p *XYPad) CreateRenderer() fyne.WidgetRenderer {
+	bg := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	bg.StrokeColor = theme.Color(theme.ColorNameInputBorder)
+	bg.StrokeWidth = 1
+	r := &xyPadRenderer{
+		pad:        p,
+		background: bg,
+		horizontal: canvas.NewLine(theme.Color(theme.ColorNameDisabled)),
+		vertical:   canvas.NewLine(theme.Color(theme.ColorNameDisabled)),
+		handle:     canvas.NewCircle(theme.Color(theme.ColorNamePrimary)),
+	}
+	return r
+}
+
+// xyPadRenderer XYPadの描画
+type xyPadRenderer struct {
+	pad        *XYPad
+	background *canvas.Rectangle
+	horizontal *canvas.Line
+	vertical   *canvas.Line
+	handle     *canvas.Circle
+}
+
+func (r *xyPadRenderer) Layout(size fyne.Size) {
+	r.background.Resize(size)
+
+	x := float32(r.pad.X) * size.Width
+	y := float32(1-r.pad.Y) * size.Height
+	r.horizontal.Position1 = fyne.NewPos(0, y)
+	r.horizontal.Position2 = fyne.NewPos(size.Width, y)
+	r.vertical.Position1 = fyne.NewPos(x, 0)
+	r.vertical.Position2 = fyne.NewPos(x, size.Height)
+
+	const handleSize = 12
+	r.handle.Move(fyne.NewPos(x-handleSize/2, y-handleSize/2))
+	r.handle.Resize(fyne.NewSize(handleSize, handleSize))
+}
+
+func (r *xyPadRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(160, 160)
+}
+
+func (r *xyPadRenderer) Refresh() {
+	r.Layout(r.pad.Size())
+	for _, obj := range r.Objects() {
+		canvas.Refresh(obj)
+	}
+}
+
+func (r *xyPadRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.background, r.horizontal, r.vertical, r.handle}
+}
+
+func (r *xyPadRenderer) Destroy() {
+}
+
+// knobSweep ノブの回転範囲（度）。最小値が左下、最大値が右下になる
+const knobSweep = 270
+
+// knobDragRange 最小値から最大値まで回すのに必要な上下のドラッグ量
+const knobDragRange = 150
+
+// Knob 上下のドラッグで値を変える回転ノブ（値は0〜1で保持する）
+type Knob struct {
+	widget.BaseWidget
+
+	Value float64
+
+	// OnChanged ドラッグで値が変わったときに呼ばれる
+	OnChanged func(value float64)
+}
+
+// NewKnob ノブを作成する
+func NewKnob(value float64, onChanged func(value float64)) *Knob {
+	k := &Knob{
+		Value:     math.Max(0, math.Min(1, value)),
+		OnChanged: onChanged,
+	}
+	k.ExtendBaseWidget(k)
+	return k
+}
+
+// Dragged 上にドラッグすると値が増え、下にドラッグすると減る
+func (k *Knob) Dragged(ev *fyne.DragEvent) {
+	value := math.Max(0, math.Min(1, k.Value-float64(ev.Dragged.DY)/knobDragRange))
+	if value == k.Value {
+		return
+	}
+	k.Value = value
+	k.Refresh()
+	if k.OnChanged != nil {
+		k.OnChanged(k.Value)
+	}
+}
+
+// DragEnd ドラッグ終了（何もしない）
+func (k *Knob) DragEnd() {
+}
+
+// CreateRenderer ウィジェットの描画を作成する
+func (k *Knob) CreateRenderer() fyne.WidgetRenderer {
+	body := canvas.NewCircle(theme.Color(theme.ColorNameInputBackground))
+	body.StrokeColor = theme.Color(theme.ColorNameInputBorder)
+	body.StrokeWidth = 1
+	pointer := canvas.NewLine(theme.Color(theme.ColorNamePrimary))
+	pointer.StrokeWidth = 3
+	return &knobRenderer{
+		knob:    k,
+		body:    body,
+		minTick: canvas.NewLine(theme.Color(theme.ColorNameDisabled)),
+		maxTick: canvas.NewLine(theme.Color(theme.ColorNameDisabled)),
+		pointer: pointer,
+	}
+}
+
+// knobRenderer Knobの描画
+type knobRenderer struct {
+	knob    *Knob
+	body    *canvas.Circle
+	minTick *canvas.Line
+	maxTick *canvas.Line
+	pointer *canvas.Line
+}
+
+// knobPoint 中心からの角度（度、右が0で反時計回り）と半径の位置を返す
+func knobPoint(center fyne.Position, angle float64, radius float32) fyne.Position {
+	rad := angle * math.Pi / 180
+	return fyne.NewPos(center.X+radius*float32(math.Cos(rad)), center.Y-radius*float32(math.Sin(rad)))
+}
+
+func (r *knobRenderer) Layout(size fyne.Size) {
+	diameter := fyne.Min(size.Width, size.Height) - 8
+	radius := diameter / 2
+	center := fyne.NewPos(size.Width/2, size.Height/2)
+	r.body.Move(fyne.NewPos(center.X-radius, center.Y-radius))
+	r.body.Resize(fyne.NewSize(diameter, diameter))
+
+	start := 90 + knobSweep/2.0
+	end := start - knobSweep
+	r.minTick.Position1 = knobPoint(center, start, radius)
+	r.minTick.Position2 = knobPoint(center, start, radius+4)
+	r.maxTick.Position1 = knobPoint(center, end, radius)
+	r.maxTick.Position2 = knobPoint(center, end, radius+4)
+
+	angle := start - r.knob.Value*knobSweep
+	r.pointer.Position1 = knobPoint(center, angle, radius*0.3)
+	r.pointer.Position2 = knobPoint(center, angle, radius*0.9)
+}
+
+func (r *knobRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(40, 40)
+}
+
+func (r *knobRenderer) Refresh() {
+	r.Layout(r.knob.Size())
+	for _, obj := range r.Objects() {
+		canvas.Refresh(obj)
+	}
+}
+
+func (r *knobRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.body, r.minTick, r.maxTick, r.pointer}
+}
+
+func (r *knobRenderer) Destroy() {
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package main
+
+import "time"
+
+// processCPUTime このプラットフォームではCPU時間を取得できない
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
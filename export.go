@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportTimeFormat エクスポート時のタイムスタンプ書式（ミリ秒まで）
+const exportTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// ExportRecord JSONエクスポートの1メッセージ分のデータ
+type ExportRecord struct {
+	Time      string        `json:"time"`
+	Source    string        `json:"source"`
+	Address   string        `json:"address"`
+	TypeTags  string        `json:"type_tags"`
+	Arguments []OSCArgument `json:"arguments"`
+}
+
+// exportFormatFromFilename ファイルの拡張子からエクスポート形式を決める
+func exportFormatFromFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	case ".pcapng":
+		return "pcapng"
+	}
+	return "text"
+}
+
+// typeTagsOf 引数リストのOSCタイプタグ文字列を返す（例: ",ifs"）
+func typeTagsOf(arguments []OSCArgument) string {
+	var tags strings.Builder
+	tags.WriteString(",")
+	for _, arg := range arguments {
+		tags.WriteString(arg.TypeTag())
+	}
+	return tags.String()
+}
+
+// formatExportTime 受信時刻をエクスポート用に整形する
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(exportTimeFormat)
+}
+
+// ExportMessages メッセージを指定形式（csv, json, text, pcapng）で書き出す
+func ExportMessages(w io.Writer, format string, msgs []OSCMessage) error {
+	switch format {
+	case "csv":
+		return exportCSV(w, msgs)
+	case "json":
+		return exportJSON(w, msgs)
+	case "text":
+		return exportText(w, msgs)
+	case "pcapng":
+		return exportPcapng(w, msgs)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
+
+// exportCSV 1メッセージ1行のCSVを書き出す。引数は arg1, arg2, ... の列に "type:value" で入れる
+func exportCSV(w io.Writer, msgs []OSCMessage) error {
+	maxArgs := 0
+	for _, msg := range msgs {
+		if len(msg.Arguments) > maxArgs {
+			maxArgs = len(msg.Arguments)
+		}
+	}
+
+	header := []string{"time", "source", "address", "type_tags"}
+	for i := 1; i <= maxArgs; i++ {
+		header = append(header, fmt.Sprintf("arg%d", i))
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		row := []string{formatExportTime(msg.Time), msg.Source, msg.Address, typeTagsOf(msg.Arguments)}
+		for _, arg := range msg.Arguments {
+			row = append(row, fmt.Sprintf("%s:%s", arg.Type, arg.Value))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportJSON メッセージの配列をJSONで書き出す
+func exportJSON(w io.Writer, msgs []OSCMessage) error {
+	records := make([]ExportRecord, 0, len(msgs))
+	for _, msg := range msgs {
+		arguments := msg.Arguments
+		if arguments == nil {
+			arguments = []OSCArgument{}
+		}
+		records = append(records, ExportRecord{
+			Time:      formatExportTime(msg.Time),
+			Source:    msg.Source,
+			Address:   msg.Address,
+			TypeTags:  typeTagsOf(msg.Arguments),
+			Arguments: arguments,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// exportText 受信ログと同じ区切りのテキストを書き出す
+func exportText(w io.Writer, msgs []OSCMessage) error {
+	for _, msg := range msgs {
+		_, err := fmt.Fprintf(w, "%s | %s | %s | %s | %s\n",
+			formatExportTime(msg.Time), msg.Source, msg.Address, typeTagsOf(msg.Arguments), formatArguments(msg.Arguments))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// generatorShapes 選択できる波形
+var generatorShapes = []string{"sine", "triangle", "saw", "square", "random", "ramp"}
+
+// generatorNone 波形を使わない場合の選択肢
+const generatorNone = "none"
+
+// GeneratorSettings 数値引数を動かす波形の設定
+type GeneratorSettings struct {
+	Shape     string  `yaml:"shape"`     // sine, triangle, saw, square, random, ramp
+	Frequency float64 `yaml:"frequency"` // Hz（randomは1秒あたりの最大変化量を範囲の何倍にするか、rampは1/frequency秒で上昇）
+	Min       float64 `yaml:"min"`
+	Max       float64 `yaml:"max"`
+	Phase     float64 `yaml:"phase,omitempty"` // 開始位置（周期に対する割合 0〜1）
+}
+
+// validate 波形の設定を検証する
+func (s GeneratorSettings) validate() error {
+	found := false
+	for _, shape := range generatorShapes {
+		if s.Shape == shape {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("unsupported waveform: %s", s.Shape)
+	}
+	if s.Frequency <= 0 {
+		return fmt.Errorf("frequency must be greater than 0: %g", s.Frequency)
+	}
+	return nil
+}
+
+// isNumericArgumentType 波形で動かせる引数タイプかどうか
+func isNumericArgumentType(typ string) bool {
+	switch typ {
+	case "int", "float", "int64", "double":
+		return true
+	}
+	return false
+}
+
+// Generator 時刻から波形の値を計算する
+type Generator struct {
+	settings GeneratorSettings
+	start    time.Time
+
+	// random用の状態
+	value float64
+	last  time.Time
+	rnd   *rand.Rand
+}
+
+// NewGenerator 設定を検証してGeneratorを作成する
+func NewGenerator(settings GeneratorSettings) (*Generator, error) {
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		settings: settings,
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	g.Reset(time.Now())
+	return g, nil
+}
+
+// Settings 波形の設定を返す
+func (g *Generator) Settings() GeneratorSettings {
+	return g.settings
+}
+
+// Reset 波形を開始位置に戻す
+func (g *Generator) Reset(now time.Time) {
+	g.start = now
+	g.last = now
+	g.value = g.settings.Min + (g.settings.Max-g.settings.Min)*math.Mod(g.settings.Phase, 1)
+}
+
+// Value 指定時刻の値を返す
+func (g *Generator) Value(now time.Time) float64 {
+	s := g.settings
+	elapsed := now.Sub(g.start).Seconds()
+	cycle := elapsed*s.Frequency + s.Phase
+	t := cycle - math.Floor(cycle) // 周期内の位置（0〜1）
+
+	var level float64 // 0〜1
+	switch s.Shape {
+	case "sine":
+		level = (math.Sin(2*math.Pi*t) + 1) / 2
+	case "triangle":
+		level = 1 - math.Abs(2*t-1)
+	case "saw":
+		level = t
+	case "square":
+		if t >= 0.5 {
+			level = 1
+		}
+	case "ramp":
+		// 一度だけ上昇して最大値で止まる
+		level = math.Min(cycle, 1)
+	case "random":
+		return g.randomWalk(now)
+	}
+	return s.Min + (s.Max-s.Min)*level
+}
+
+// randomWalk 前回の値からランダムに移動した値を返す（範囲の端では折り返す）
+func (g *Generator) randomWalk(now time.Time) float64 {
+	s := g.settings
+	dt := now.Sub(g.last).Seconds()
+	g.last = now
+	if dt <= 0 {
+		return g.value
+	}
+
+	low, high := math.Min(s.Min, s.Max), math.Max(s.Min, s.Max)
+	step := (high - low) * s.Frequency * dt
+	g.value += (g.rnd.Float64()*2 - 1) * step
+	for g.value < low || g.value > high {
+		if g.value < low {
+			g.value = 2*low - g.value
+		} else {
+			g.value = 2*high - g.value
+		}
+		if high == low {
+			g.value = low
+		}
+	}
+	return g.value
+}
+
+// Argument 指定時刻の値を引数タイプに合わせて返す
+func (g *Generator) Argument(typ string, now time.Time) OSCArgument {
+	value := g.Value(now)
+	switch typ {
+	case "int":
+		return OSCArgument{Type: typ, Value: strconv.FormatInt(int64(int32(math.Round(value))), 10)}
+	case "int64":
+		return OSCArgument{Type: typ, Value: strconv.FormatInt(int64(math.Round(value)), 10)}
+	case "float":
+		return OSCArgument{Type: typ, Value: strconv.FormatFloat(value, 'g', 6, 32)}
+	}
+	return OSCArgument{Type: "double", Value: strconv.FormatFloat(value, 'g', 10, 64)}
+}
+
+// showGeneratorDialog 波形の設定ダイアログを表示する（noneを選ぶとnilを渡す）
+func showGeneratorDialog(parent fyne.Window, current *Generator, onSubmit func(*Generator)) {
+	settings := GeneratorSettings{Shape: "sine", Frequency: 1, Min: 0, Max: 1}
+	if current != nil {
+		settings = current.Settings()
+	}
+
+	shapeSelect := widget.NewSelect(append([]string{generatorNone}, generatorShapes...), nil)
+	shapeSelect.SetSelected(settings.Shape)
+	if current == nil {
+		shapeSelect.SetSelected(generatorNone)
+	}
+	frequencyEntry := widget.NewEntry()
+	frequencyEntry.SetText(strconv.FormatFloat(settings.Frequency, 'g', -1, 64))
+	minEntry := widget.NewEntry()
+	minEntry.SetText(strconv.FormatFloat(settings.Min, 'g', -1, 64))
+	maxEntry := widget.NewEntry()
+	maxEntry.SetText(strconv.FormatFloat(settings.Max, 'g', -1, 64))
+	phaseEntry := widget.NewEntry()
+	phaseEntry.SetText(strconv.FormatFloat(settings.Phase, 'g', -1, 64))
+	phaseEntry.SetPlaceHolder("0-1 (fraction of a cycle)")
+
+	dialog.ShowForm("Generator", "OK", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Shape", shapeSelect),
+		widget.NewFormItem("Frequency (Hz)", frequencyEntry),
+		widget.NewFormItem("Min", minEntry),
+		widget.NewFormItem("Max", maxEntry),
+		widget.NewFormItem("Phase", phaseEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		if shapeSelect.Selected == generatorNone {
+			onSubmit(nil)
+			return
+		}
+
+		var values [4]float64
+		for i, entry := range []*widget.Entry{frequencyEntry, minEntry, maxEntry, phaseEntry} {
+			text := entry.Text
+			if text == "" {
+				text = "0"
+			}
+			val, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("not a valid number: %s", entry.Text), parent)
+				return
+			}
+			values[i] = val
+		}
+
+		g, err := NewGenerator(GeneratorSettings{
+			Shape:     shapeSelect.Selected,
+			Frequency: values[0],
+			Min:       values[1],
+			Max:       values[2],
+			Phase:     values[3],
+		})
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+		onSubmit(g)
+	}, parent)
+}
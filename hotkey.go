@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// hotkeyNone 既定の数字キーも割り当てないときのhotkeyの値
+const hotkeyNone = "none"
+
+// defaultHotkeyCount 数字キー1〜9を割り当てる先頭のカードの数
+const defaultHotkeyCount = 9
+
+// Hotkey 送信先を送信するキーの組み合わせ（Keyが空なら割り当てなし）
+type Hotkey struct {
+	Key      fyne.KeyName
+	Modifier fyne.KeyModifier
+}
+
+// hotkeyNamedKeys 名前で指定できるキー（小文字で比較する）
+var hotkeyNamedKeys = map[string]fyne.KeyName{
+	"space":     fyne.KeySpace,
+	"up":        fyne.KeyUp,
+	"down":      fyne.KeyDown,
+	"left":      fyne.KeyLeft,
+	"right":     fyne.KeyRight,
+	"home":      fyne.KeyHome,
+	"end":       fyne.KeyEnd,
+	"pageup":    fyne.KeyPageUp,
+	"pagedown":  fyne.KeyPageDown,
+	"insert":    fyne.KeyInsert,
+	"delete":    fyne.KeyDelete,
+	"backspace": fyne.KeyBackspace,
+}
+
+// parseHotkey "F1"、"Q"、"Ctrl+1"、"Ctrl+Shift+F5" などを読み取る
+// Shiftだけの組み合わせは入力中の文字と区別できないので使えない
+func parseHotkey(text string) (Hotkey, error) {
+	var h Hotkey
+	parts := strings.Split(strings.TrimSpace(text), "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 {
+			switch strings.ToLower(part) {
+			case "ctrl", "control":
+				h.Modifier |= fyne.KeyModifierControl
+			case "alt", "option":
+				h.Modifier |= fyne.KeyModifierAlt
+			case "shift":
+				h.Modifier |= fyne.KeyModifierShift
+			case "super", "cmd", "command", "win":
+				h.Modifier |= fyne.KeyModifierSuper
+			default:
+				return Hotkey{}, fmt.Errorf("unknown modifier %q (use Ctrl, Alt, Shift or Super)", part)
+			}
+			continue
+		}
+
+		key, ok := hotkeyKeyName(part)
+		if !ok {
+			return Hotkey{}, fmt.Errorf("unsupported key %q (use 0-9, A-Z, F1-F12, Space, arrow keys, etc.)", part)
+		}
+		h.Key = key
+	}
+	if h.Modifier == fyne.KeyModifierShift {
+		return Hotkey{}, fmt.Errorf("Shift alone is not allowed; combine it with Ctrl, Alt or Super: %s", text)
+	}
+	return h, nil
+}
+
+// hotkeyKeyName キーの名前をfyne.KeyNameにする
+func hotkeyKeyName(name string) (fyne.KeyName, bool) {
+	upper := strings.ToUpper(name)
+	if len(upper) == 1 && (upper[0] >= '0' && upper[0] <= '9' || upper[0] >= 'A' && upper[0] <= 'Z') {
+		return fyne.KeyName(upper), true
+	}
+	if strings.HasPrefix(upper, "F") {
+		if n, err := strconv.Atoi(upper[1:]); err == nil && n >= 1 && n <= 12 {
+			return fyne.KeyName(upper), true
+		}
+	}
+	key, ok := hotkeyNamedKeys[strings.ToLower(name)]
+	return key, ok
+}
+
+// String "Ctrl+Shift+F5" の形式にする（割り当てなしなら空）
+func (h Hotkey) String() string {
+	if h.Key == "" {
+		return ""
+	}
+	var parts []string
+	for _, m := range []struct {
+		modifier fyne.KeyModifier
+		name     string
+	}{
+		{fyne.KeyModifierControl, "Ctrl"},
+		{fyne.KeyModifierAlt, "Alt"},
+		{fyne.KeyModifierShift, "Shift"},
+		{fyne.KeyModifierSuper, "Super"},
+	} {
+		if h.Modifier&m.modifier != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, string(h.Key)), "+")
+}
+
+// assignHotkeys 送信先ごとのホットキーを決める
+// hotkeyを指定した送信先を優先し、指定のない先頭9枚には空いている数字キー1〜9を割り当てる
+// 読み取れない・重複したhotkeyは割り当てない（設定ファイルの検証で報告する）
+func assignHotkeys(targets []SenderTarget) []Hotkey {
+	hotkeys := make([]Hotkey, len(targets))
+	used := map[Hotkey]bool{}
+	for i, target := range targets {
+		if target.Hotkey == "" || strings.EqualFold(target.Hotkey, hotkeyNone) {
+			continue
+		}
+		h, err := parseHotkey(target.Hotkey)
+		if err != nil || used[h] {
+			continue
+		}
+		hotkeys[i], used[h] = h, true
+	}
+
+	for i := 0; i < len(targets) && i < defaultHotkeyCount; i++ {
+		if targets[i].Hotkey != "" {
+			continue
+		}
+		h := Hotkey{Key: fyne.KeyName(strconv.Itoa(i + 1))}
+		if !used[h] {
+			hotkeys[i], used[h] = h, true
+		}
+	}
+	return hotkeys
+}
+
+// SetHotkey カードに割り当てたホットキーを表示する
+func (s *senderSectionState) SetHotkey(h Hotkey) {
+	if s.hotkeyLabel == nil {
+		return
+	}
+	if h.Key == "" {
+		s.hotkeyLabel.Hide()
+		return
+	}
+	s.hotkeyLabel.SetText(fmt.Sprintf("[%s]", h))
+	s.hotkeyLabel.Show()
+}
+
+// Fire ホットキーが押されたときに現在の内容で送信し、カードを光らせる（送信できたらtrue）
+func (s *senderSectionState) Fire() bool {
+	return s.fire != nil && s.fire()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+)
+
+func TestParseHotkey(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Hotkey
+		wantErr bool
+	}{
+		{"F1", Hotkey{Key: fyne.KeyF1}, false},
+		{"f12", Hotkey{Key: fyne.KeyF12}, false},
+		{"q", Hotkey{Key: fyne.KeyQ}, false},
+		{"7", Hotkey{Key: fyne.Key7}, false},
+		{"Space", Hotkey{Key: fyne.KeySpace}, false},
+		{"pageup", Hotkey{Key: fyne.KeyPageUp}, false},
+		{"Ctrl+1", Hotkey{Key: fyne.Key1, Modifier: fyne.KeyModifierControl}, false},
+		{" ctrl + shift + F5 ", Hotkey{Key: fyne.KeyF5, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}, false},
+		{"Cmd+Up", Hotkey{Key: fyne.KeyUp, Modifier: fyne.KeyModifierSuper}, false},
+		{"Option+Left", Hotkey{Key: fyne.KeyLeft, Modifier: fyne.KeyModifierAlt}, false},
+		{"Shift+A", Hotkey{}, true}, // Shiftだけは文字の入力と区別できない
+		{"Hyper+A", Hotkey{}, true},
+		{"F13", Hotkey{}, true},
+		{"F0", Hotkey{}, true},
+		{"Enter", Hotkey{}, true},
+		{"AB", Hotkey{}, true},
+		{"", Hotkey{}, true},
+		{"Ctrl+", Hotkey{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseHotkey(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHotkey(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseHotkey(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHotkeyString(t *testing.T) {
+	for _, text := range []string{"F1", "Q", "Ctrl+1", "Ctrl+Shift+F5", "Alt+Super+Space"} {
+		h, err := parseHotkey(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.String() != text {
+			t.Errorf("String() = %q, want %q", h.String(), text)
+		}
+	}
+}
+
+func TestAssignHotkeys(t *testing.T) {
+	targets := []SenderTarget{
+		{Name: "A"},                 // 1
+		{Name: "B", Hotkey: "1"},    // 指定が優先される
+		{Name: "C", Hotkey: "none"}, // 割り当てなし
+		{Name: "D", Hotkey: "1"},    // Bと重複するので割り当てない
+		{Name: "E", Hotkey: "Shift+X"},
+		{Name: "F"},
+	}
+	want := []string{"", "1", "", "", "", "6"}
+	hotkeys := assignHotkeys(targets)
+	for i, h := range hotkeys {
+		if h.String() != want[i] {
+			t.Errorf("%s = %q, want %q", targets[i].Name, h, want[i])
+		}
+	}
+}
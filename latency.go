@@ -0,0 +1,604 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// LatencyOptions 往復遅延測定の設定
+type LatencyOptions struct {
+	Host         string
+	Port         int
+	Address      string        // プローブのアドレス
+	ReplyAddress string        // 応答のアドレス（空=プローブと同じ）
+	ListenPort   int           // 応答を受け取るポート（0=送信元ポートで受け取る）
+	Count        int           // プローブの送信回数
+	Interval     time.Duration // プローブの送信間隔
+	Timeout      time.Duration // 応答待ちのタイムアウト
+	Sequence     bool          // プローブに連番を付けて応答と照合する（false=送信順に照合）
+}
+
+// LatencyStats 往復遅延の集計結果
+type LatencyStats struct {
+	Sent     int
+	Received int
+	Timeouts int
+	Min      time.Duration
+	Avg      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+	RTTs     []time.Duration // 受信順
+}
+
+// percentile ソート済みの値からパーセンタイル値を返す（最近傍法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+// computeLatencyStats 往復遅延の一覧から統計を計算する
+func computeLatencyStats(sent, timeouts int, rtts []time.Duration) LatencyStats {
+	stats := LatencyStats{
+		Sent:     sent,
+		Received: len(rtts),
+		Timeouts: timeouts,
+		RTTs:     append([]time.Duration(nil), rtts...),
+	}
+	if len(rtts) == 0 {
+		return stats
+	}
+
+	sorted := append([]time.Duration(nil), rtts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, rtt := range sorted {
+		total += rtt
+	}
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Avg = total / time.Duration(len(sorted))
+	stats.P95 = percentile(sorted, 95)
+	stats.P99 = percentile(sorted, 99)
+	return stats
+}
+
+// LatencyTest プローブを送信し、応答までの往復時間を測定する
+type LatencyTest struct {
+	opts LatencyOptions
+	conn net.PacketConn
+
+	// OnUpdate 応答受信またはタイムアウトのたびに呼ばれる
+	OnUpdate func(stats LatencyStats)
+
+	mu       sync.Mutex
+	pending  map[int32]time.Time // 応答待ちのプローブ（連番 → 送信時刻）
+	order    []int32             // 応答待ちのプローブの送信順
+	sent     int
+	timeouts int
+	rtts     []time.Duration
+
+	stopCh  chan struct{}
+	stopped sync.Once
+}
+
+// NewLatencyTest 応答用のソケットを開いてLatencyTestを作成する
+func NewLatencyTest(opts LatencyOptions) (*LatencyTest, error) {
+	if opts.Host == "" || opts.Port <= 0 || opts.Address == "" {
+		return nil, errors.New("enter the host, port and address")
+	}
+	if opts.Count <= 0 || opts.Interval <= 0 || opts.Timeout <= 0 {
+		return nil, errors.New("count, interval and timeout must be greater than 0")
+	}
+	if opts.ReplyAddress == "" {
+		opts.ReplyAddress = opts.Address
+	}
+
+	conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", opts.ListenPort))
+	if err != nil {
+		return nil, err
+	}
+	return &LatencyTest{
+		opts:    opts,
+		conn:    conn,
+		pending: map[int32]time.Time{},
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// Run 測定を行い、結果を返す。停止されるか全プローブの応答またはタイムアウトまで戻らない
+func (t *LatencyTest) Run() (LatencyStats, error) {
+	defer t.conn.Close()
+
+	dest, err := net.ResolveUDPAddr("udp", net.JoinHostPort(t.opts.Host, strconv.Itoa(t.opts.Port)))
+	if err != nil {
+		return t.Stats(), err
+	}
+
+	go t.receive()
+
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+
+	for seq := int32(1); int(seq) <= t.opts.Count; seq++ {
+		msg := osc.NewMessage(t.opts.Address)
+		if t.opts.Sequence {
+			msg.Append(seq)
+		}
+		data, err := msg.MarshalBinary()
+		if err != nil {
+			return t.Stats(), err
+		}
+
+		t.mu.Lock()
+		t.pending[seq] = time.Now()
+		t.order = append(t.order, seq)
+		t.sent++
+		t.mu.Unlock()
+
+		if _, err := t.conn.WriteTo(data, dest); err != nil {
+			return t.Stats(), err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-t.stopCh:
+			return t.Stats(), nil
+		}
+		t.expire()
+	}
+
+	// 最後のプローブの応答を待つ
+	deadline := time.NewTimer(t.opts.Timeout)
+	defer deadline.Stop()
+	for {
+		t.mu.Lock()
+		remaining := len(t.pending)
+		t.mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		select {
+		case <-deadline.C:
+			t.expireAll()
+			return t.Stats(), nil
+		case <-t.stopCh:
+			return t.Stats(), nil
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return t.Stats(), nil
+}
+
+// receive 応答を受信して対応するプローブの往復時間を記録する
+func (t *LatencyTest) receive() {
+	data := make([]byte, 65535)
+	for {
+		n, _, err := t.conn.ReadFrom(data)
+		if err != nil {
+			return
+		}
+		received := time.Now()
+
+		packet, err := osc.ParsePacket(string(data[:n]))
+		if err != nil {
+			continue
+		}
+		for _, msg := range flattenPacket(packet) {
+			if msg.Address != t.opts.ReplyAddress {
+				continue
+			}
+			t.match(msg, received)
+		}
+	}
+}
+
+// match 応答をプローブと照合する
+func (t *LatencyTest) match(msg *osc.Message, received time.Time) {
+	t.mu.Lock()
+
+	var seq int32
+	found := false
+	if t.opts.Sequence {
+		// 応答に含まれる整数引数のうち応答待ちの連番と一致するもの
+		for _, arg := range msg.Arguments {
+			var candidate int32
+			switch v := arg.(type) {
+			case int32:
+				candidate = v
+			case int64:
+				candidate = int32(v)
+			case float32:
+				candidate = int32(v)
+			default:
+				continue
+			}
+			if _, ok := t.pending[candidate]; ok {
+				seq, found = candidate, true
+				break
+			}
+		}
+	} else {
+		// 連番がない場合は最も古い応答待ちのプローブとみなす
+		for _, candidate := range t.order {
+			if _, ok := t.pending[candidate]; ok {
+				seq, found = candidate, true
+				break
+			}
+		}
+	}
+
+	if !found {
+		t.mu.Unlock()
+		return
+	}
+	t.rtts = append(t.rtts, received.Sub(t.pending[seq]))
+	delete(t.pending, seq)
+	t.mu.Unlock()
+
+	t.notify()
+}
+
+// expire タイムアウトしたプローブを応答待ちから外す
+func (t *LatencyTest) expire() {
+	t.mu.Lock()
+	expired := 0
+	now := time.Now()
+	for seq, sentAt := range t.pending {
+		if now.Sub(sentAt) > t.opts.Timeout {
+			delete(t.pending, seq)
+			expired++
+		}
+	}
+	t.timeouts += expired
+	t.mu.Unlock()
+
+	if expired > 0 {
+		t.notify()
+	}
+}
+
+// expireAll 応答待ちのプローブをすべてタイムアウトにする
+func (t *LatencyTest) expireAll() {
+	t.mu.Lock()
+	t.timeouts += len(t.pending)
+	t.pending = map[int32]time.Time{}
+	t.mu.Unlock()
+	t.notify()
+}
+
+// notify 途中経過を通知する
+func (t *LatencyTest) notify() {
+	if t.OnUpdate != nil {
+		t.OnUpdate(t.Stats())
+	}
+}
+
+// Stats 現在までの集計結果を返す
+func (t *LatencyTest) Stats() LatencyStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return computeLatencyStats(t.sent, t.timeouts, t.rtts)
+}
+
+// Stop 測定を停止する
+func (t *LatencyTest) Stop() {
+	t.stopped.Do(func() {
+		close(t.stopCh)
+	})
+}
+
+// formatMilliseconds 時間をミリ秒で表示する
+func formatMilliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f ms", float64(d)/float64(time.Millisecond))
+}
+
+// histogram 往復遅延をbins個の区間に分けて数える
+func histogram(rtts []time.Duration, bins int) (counts []int, min, width time.Duration) {
+	counts = make([]int, bins)
+	if len(rtts) == 0 {
+		return counts, 0, 0
+	}
+	min, max := rtts[0], rtts[0]
+	for _, rtt := range rtts {
+		if rtt < min {
+			min = rtt
+		}
+		if rtt > max {
+			max = rtt
+		}
+	}
+	width = (max - min) / time.Duration(bins)
+	if width <= 0 {
+		width = time.Microsecond
+	}
+	for _, rtt := range rtts {
+		i := int((rtt - min) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		counts[i]++
+	}
+	return counts, min, width
+}
+
+// WriteLatencyReport 測定結果をテキストのレポートとして書き出す
+func WriteLatencyReport(w io.Writer, opts LatencyOptions, stats LatencyStats) error {
+	loss := 0.0
+	if stats.Sent > 0 {
+		loss = float64(stats.Timeouts) / float64(stats.Sent) * 100
+	}
+
+	fmt.Fprintf(w, "OSC Round-trip Latency Report\n")
+	fmt.Fprintf(w, "Date:        %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(w, "Target:      %s:%d\n", opts.Host, opts.Port)
+	fmt.Fprintf(w, "Probe:       %s (sequence: %v)\n", opts.Address, opts.Sequence)
+	fmt.Fprintf(w, "Reply:       %s\n", opts.ReplyAddress)
+	fmt.Fprintf(w, "Interval:    %s\n", opts.Interval)
+	fmt.Fprintf(w, "Timeout:     %s\n\n", opts.Timeout)
+	fmt.Fprintf(w, "Sent:        %d\n", stats.Sent)
+	fmt.Fprintf(w, "Received:    %d\n", stats.Received)
+	fmt.Fprintf(w, "Timeouts:    %d (%.1f%%)\n\n", stats.Timeouts, loss)
+	fmt.Fprintf(w, "Min:         %s\n", formatMilliseconds(stats.Min))
+	fmt.Fprintf(w, "Avg:         %s\n", formatMilliseconds(stats.Avg))
+	fmt.Fprintf(w, "P95:         %s\n", formatMilliseconds(stats.P95))
+	fmt.Fprintf(w, "P99:         %s\n", formatMilliseconds(stats.P99))
+	fmt.Fprintf(w, "Max:         %s\n\n", formatMilliseconds(stats.Max))
+
+	counts, min, width := histogram(stats.RTTs, 20)
+	fmt.Fprintf(w, "Histogram:\n")
+	for i, count := range counts {
+		from := min + width*time.Duration(i)
+		fmt.Fprintf(w, "  %12s - %12s  %5d\n", formatMilliseconds(from), formatMilliseconds(from+width), count)
+	}
+
+	fmt.Fprintf(w, "\nSamples (ms):\n")
+	for _, rtt := range stats.RTTs {
+		if _, err := fmt.Fprintf(w, "%.3f\n", float64(rtt)/float64(time.Millisecond)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createLatencyWindow 往復遅延測定ウィンドウを作成
+func createLatencyWindow(a fyne.App, target SenderTarget) fyne.Window {
+	win := a.NewWindow("Latency Test")
+
+	hostEntry := widget.NewEntry()
+	hostEntry.SetText(target.Host)
+	portEntry := widget.NewEntry()
+	portEntry.SetText(fmt.Sprintf("%d", target.Port))
+	addressEntry := widget.NewEntry()
+	addressEntry.SetText("/ping")
+	replyEntry := widget.NewEntry()
+	replyEntry.SetPlaceHolder("same as probe")
+	listenEntry := widget.NewEntry()
+	listenEntry.SetPlaceHolder("0 = reply to source port")
+	countEntry := widget.NewEntry()
+	countEntry.SetText("100")
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText("100")
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetText("1000")
+	sequenceCheck := widget.NewCheck("Append sequence number", nil)
+	sequenceCheck.SetChecked(true)
+
+	statsLabel := widget.NewLabel("Not started")
+
+	// ヒストグラム描画領域
+	const histWidth, histHeight, histBins = 520, 140, 20
+	histContainer := container.NewWithoutLayout()
+	histRange := widget.NewLabel("")
+	drawHistogram := func(rtts []time.Duration) {
+		histContainer.RemoveAll()
+		counts, min, width := histogram(rtts, histBins)
+		maxCount := 0
+		for _, count := range counts {
+			if count > maxCount {
+				maxCount = count
+			}
+		}
+		barWidth := float32(histWidth) / histBins
+		for i, count := range counts {
+			if count == 0 {
+				continue
+			}
+			height := float32(histHeight) * float32(count) / float32(maxCount)
+			bar := canvas.NewRectangle(theme.Color(theme.ColorNamePrimary))
+			bar.Move(fyne.NewPos(float32(i)*barWidth+1, histHeight-height))
+			bar.Resize(fyne.NewSize(barWidth-2, height))
+			histContainer.Add(bar)
+		}
+		baseline := canvas.NewLine(color.Gray{Y: 128})
+		baseline.Position1 = fyne.NewPos(0, histHeight)
+		baseline.Position2 = fyne.NewPos(histWidth, histHeight)
+		histContainer.Add(baseline)
+		histContainer.Refresh()
+
+		if len(rtts) > 0 {
+			histRange.SetText(fmt.Sprintf("%s … %s (peak %d)", formatMilliseconds(min), formatMilliseconds(min+width*histBins), maxCount))
+		} else {
+			histRange.SetText("")
+		}
+	}
+	histArea := container.NewGridWrap(fyne.NewSize(histWidth, histHeight+2), histContainer)
+
+	showStats := func(stats LatencyStats) {
+		statsLabel.SetText(fmt.Sprintf(
+			"Sent %d / Received %d / Timeouts %d\nMin %s  Avg %s  P95 %s  P99 %s  Max %s",
+			stats.Sent, stats.Received, stats.Timeouts,
+			formatMilliseconds(stats.Min), formatMilliseconds(stats.Avg),
+			formatMilliseconds(stats.P95), formatMilliseconds(stats.P99), formatMilliseconds(stats.Max)))
+		drawHistogram(stats.RTTs)
+	}
+
+	var test *LatencyTest
+	var lastOpts LatencyOptions
+	var lastStats LatencyStats
+	var startBtn, stopBtn, exportBtn *widget.Button
+
+	startBtn = widget.NewButton("Start", func() {
+		port, err := strconv.Atoi(portEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid port number: %s", portEntry.Text), win)
+			return
+		}
+		listenPort := 0
+		if listenEntry.Text != "" {
+			if listenPort, err = strconv.Atoi(listenEntry.Text); err != nil {
+				dialog.ShowError(fmt.Errorf("invalid port number: %s", listenEntry.Text), win)
+				return
+			}
+		}
+		count, err := strconv.Atoi(countEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid count: %s", countEntry.Text), win)
+			return
+		}
+		interval, err := strconv.Atoi(intervalEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid interval: %s", intervalEntry.Text), win)
+			return
+		}
+		timeout, err := strconv.Atoi(timeoutEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid timeout: %s", timeoutEntry.Text), win)
+			return
+		}
+
+		opts := LatencyOptions{
+			Host:         hostEntry.Text,
+			Port:         port,
+			Address:      addressEntry.Text,
+			ReplyAddress: replyEntry.Text,
+			ListenPort:   listenPort,
+			Count:        count,
+			Interval:     time.Duration(interval) * time.Millisecond,
+			Timeout:      time.Duration(timeout) * time.Millisecond,
+			Sequence:     sequenceCheck.Checked,
+		}
+		t, err := NewLatencyTest(opts)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		if opts.ReplyAddress == "" {
+			opts.ReplyAddress = opts.Address
+		}
+
+		// 描画が追いつかないほど応答が多い場合に備えて更新を間引く
+		var lastDraw time.Time
+		t.OnUpdate = func(stats LatencyStats) {
+			fyne.Do(func() {
+				if time.Since(lastDraw) < 100*time.Millisecond {
+					return
+				}
+				lastDraw = time.Now()
+				showStats(stats)
+			})
+		}
+		test = t
+		lastOpts = opts
+
+		startBtn.Disable()
+		stopBtn.Enable()
+		exportBtn.Disable()
+		log.Printf("遅延測定を開始: %s:%d %s (%d回)", opts.Host, opts.Port, opts.Address, opts.Count)
+
+		go func() {
+			stats, err := t.Run()
+			fyne.Do(func() {
+				if err != nil {
+					log.Printf("遅延測定エラー: %v", err)
+					dialog.ShowError(err, win)
+				}
+				lastStats = stats
+				showStats(stats)
+				startBtn.Enable()
+				stopBtn.Disable()
+				exportBtn.Enable()
+			})
+			log.Printf("遅延測定を終了: 受信 %d / 送信 %d, 平均 %s", stats.Received, stats.Sent, formatMilliseconds(stats.Avg))
+		}()
+	})
+
+	stopBtn = widget.NewButton("Stop", func() {
+		if test != nil {
+			test.Stop()
+		}
+	})
+	stopBtn.Disable()
+
+	exportBtn = widget.NewButton("Export Report", func() {
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+			if err := WriteLatencyReport(writer, lastOpts, lastStats); err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			log.Printf("遅延測定レポートを保存: %s", writer.URI().Path())
+		}, win)
+		saveDialog.SetFileName(fmt.Sprintf("latency-%s.txt", time.Now().Format("20060102-150405")))
+		saveDialog.Show()
+	})
+	exportBtn.Disable()
+
+	form := widget.NewForm(
+		widget.NewFormItem("Host", hostEntry),
+		widget.NewFormItem("Port", portEntry),
+		widget.NewFormItem("Probe Address", addressEntry),
+		widget.NewFormItem("Reply Address", replyEntry),
+		widget.NewFormItem("Listen Port", listenEntry),
+		widget.NewFormItem("Count", countEntry),
+		widget.NewFormItem("Interval (ms)", intervalEntry),
+		widget.NewFormItem("Timeout (ms)", timeoutEntry),
+		widget.NewFormItem("", sequenceCheck),
+	)
+
+	win.SetContent(container.NewVScroll(container.NewVBox(
+		widget.NewCard("Latency Test", "Measure round-trip time of an echoed probe", nil),
+		form,
+		widget.NewSeparator(),
+		container.NewHBox(startBtn, stopBtn, layout.NewSpacer(), exportBtn),
+		statsLabel,
+		histArea,
+		histRange,
+	)))
+	win.Resize(fyne.NewSize(580, 720))
+	win.SetCloseIntercept(func() {
+		if test != nil {
+			test.Stop()
+		}
+		win.Hide()
+	})
+	return win
+}
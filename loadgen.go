@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// LoadOptions 負荷試験の設定
+type LoadOptions struct {
+	Host      string
+	Port      int
+	Address   string
+	Arguments []OSCArgument
+	Rate      float64       // 1秒あたりの送信数
+	Burst     int           // 1回にまとめて送信する数
+	Duration  time.Duration // 試験時間（0=停止するまで）
+	Increment bool          // 数値引数を送信ごとに1ずつ増やす
+}
+
+// LoadStats 負荷試験の途中経過・結果
+type LoadStats struct {
+	Sent    uint64
+	Errors  uint64
+	Elapsed time.Duration
+	Rate    float64 // 実際の送信レート（msg/s）
+	CPU     float64 // プロセスのCPU使用率（%、取得できない場合は-1）
+}
+
+// LoadGenerator 指定したレートでメッセージを送信し続ける
+type LoadGenerator struct {
+	opts LoadOptions
+
+	// OnUpdate 一定間隔で途中経過を通知する
+	OnUpdate func(stats LoadStats)
+
+	sent    atomic.Uint64
+	errors  atomic.Uint64
+	stopCh  chan struct{}
+	stopped sync.Once
+}
+
+// NewLoadGenerator 設定を検証してLoadGeneratorを作成する
+func NewLoadGenerator(opts LoadOptions) (*LoadGenerator, error) {
+	if opts.Host == "" || opts.Port <= 0 || opts.Address == "" {
+		return nil, errors.New("enter the host, port and address")
+	}
+	if opts.Rate <= 0 {
+		return nil, fmt.Errorf("invalid rate: %g", opts.Rate)
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = 1
+	}
+	// 引数を事前に検証しておく
+	if _, err := buildOSCMessage(opts.Address, opts.Arguments); err != nil {
+		return nil, err
+	}
+	return &LoadGenerator{
+		opts:   opts,
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// message n番目（0始まり）に送信するメッセージのバイト列を作る
+func (g *LoadGenerator) message(n uint64) ([]byte, error) {
+	arguments := g.opts.Arguments
+	if g.opts.Increment {
+		arguments = incrementArguments(arguments, n)
+	}
+	msg, err := buildOSCMessage(g.opts.Address, arguments)
+	if err != nil {
+		return nil, err
+	}
+	return msg.MarshalBinary()
+}
+
+// incrementArguments 数値引数にnを加えた引数リストを返す
+func incrementArguments(arguments []OSCArgument, n uint64) []OSCArgument {
+	result := make([]OSCArgument, len(arguments))
+	for i, arg := range arguments {
+		result[i] = arg
+		switch arg.Type {
+		case "int":
+			if val, err := strconv.ParseInt(arg.Value, 10, 32); err == nil {
+				result[i].Value = strconv.FormatInt(int64(int32(val+int64(n))), 10)
+			}
+		case "int64":
+			if val, err := strconv.ParseInt(arg.Value, 10, 64); err == nil {
+				result[i].Value = strconv.FormatInt(val+int64(n), 10)
+			}
+		case "float":
+			if val, err := strconv.ParseFloat(arg.Value, 32); err == nil {
+				result[i].Value = strconv.FormatFloat(val+float64(n), 'g', -1, 32)
+			}
+		case "double":
+			if val, err := strconv.ParseFloat(arg.Value, 64); err == nil {
+				result[i].Value = strconv.FormatFloat(val+float64(n), 'g', -1, 64)
+			}
+		}
+	}
+	return result
+}
+
+// Run 負荷試験を行う。停止されるか試験時間が経過するまで戻らない
+func (g *LoadGenerator) Run() (LoadStats, error) {
+	conn, err := net.Dial("udp", net.JoinHostPort(g.opts.Host, strconv.Itoa(g.opts.Port)))
+	if err != nil {
+		return LoadStats{}, err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	cpuStart, cpuOK := processCPUTime()
+	lastReport := start
+	burstInterval := time.Duration(float64(g.opts.Burst) / g.opts.Rate * float64(time.Second))
+
+	// stats 開始時点からの集計を作る
+	stats := func(now time.Time) LoadStats {
+		elapsed := now.Sub(start)
+		s := LoadStats{
+			Sent:    g.sent.Load(),
+			Errors:  g.errors.Load(),
+			Elapsed: elapsed,
+			CPU:     -1,
+		}
+		if elapsed > 0 {
+			s.Rate = float64(s.Sent) / elapsed.Seconds()
+			if cpu, ok := processCPUTime(); ok && cpuOK {
+				s.CPU = float64(cpu-cpuStart) / float64(elapsed) * 100
+			}
+		}
+		return s
+	}
+
+	var n uint64
+	for burst := 0; ; burst++ {
+		// 予定時刻まで待つ（遅れている場合は待たずに送信して追いつく）
+		next := start.Add(time.Duration(burst) * burstInterval)
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-g.stopCh:
+				return stats(time.Now()), nil
+			}
+		} else {
+			select {
+			case <-g.stopCh:
+				return stats(time.Now()), nil
+			default:
+			}
+		}
+
+		now := time.Now()
+		if g.opts.Duration > 0 && now.Sub(start) >= g.opts.Duration {
+			return stats(now), nil
+		}
+
+		for i := 0; i < g.opts.Burst; i++ {
+			data, err := g.message(n)
+			n++
+			if err == nil {
+				_, err = conn.Write(data)
+			}
+			if err != nil {
+				g.errors.Add(1)
+				continue
+			}
+			g.sent.Add(1)
+		}
+
+		if g.OnUpdate != nil && now.Sub(lastReport) >= 250*time.Millisecond {
+			lastReport = now
+			g.OnUpdate(stats(now))
+		}
+	}
+}
+
+// Stop 負荷試験を停止する
+func (g *LoadGenerator) Stop() {
+	g.stopped.Do(func() {
+		close(g.stopCh)
+	})
+}
+
+// formatLoadStats 負荷試験の経過を1行の文字列にする
+func formatLoadStats(stats LoadStats) string {
+	cpu := "n/a"
+	if stats.CPU >= 0 {
+		cpu = fmt.Sprintf("%.1f%%", stats.CPU)
+	}
+	return fmt.Sprintf("Sent %d / Errors %d / %.1fs / %.0f msg/s / CPU %s",
+		stats.Sent, stats.Errors, stats.Elapsed.Seconds(), stats.Rate, cpu)
+}
+
+// createLoadWindow 負荷試験ウィンドウを作成
+func createLoadWindow(a fyne.App, name, host string, port int, address string, arguments []OSCArgument) fyne.Window {
+	win := a.NewWindow(fmt.Sprintf("Load Generator - %s", name))
+
+	rateEntry := widget.NewEntry()
+	rateEntry.SetText("1000")
+	burstEntry := widget.NewEntry()
+	burstEntry.SetText("1")
+	durationEntry := widget.NewEntry()
+	durationEntry.SetText("10")
+	durationEntry.SetPlaceHolder("0 = until stopped")
+	incrementCheck := widget.NewCheck("Increment numeric arguments", nil)
+
+	statsLabel := widget.NewLabel("Not started")
+	progressBar := widget.NewProgressBar()
+
+	var generator *LoadGenerator
+	var startBtn, stopBtn *widget.Button
+
+	startBtn = widget.NewButton("Start", func() {
+		rate, err := strconv.ParseFloat(rateEntry.Text, 64)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid rate: %s", rateEntry.Text), win)
+			return
+		}
+		burst, err := strconv.Atoi(burstEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid burst: %s", burstEntry.Text), win)
+			return
+		}
+		duration, err := durationFromSeconds(durationEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+
+		opts := LoadOptions{
+			Host:      host,
+			Port:      port,
+			Address:   address,
+			Arguments: arguments,
+			Rate:      rate,
+			Burst:     burst,
+			Duration:  duration,
+			Increment: incrementCheck.Checked,
+		}
+		g, err := NewLoadGenerator(opts)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		g.OnUpdate = func(stats LoadStats) {
+			fyne.Do(func() {
+				statsLabel.SetText(formatLoadStats(stats))
+				if duration > 0 {
+					progressBar.SetValue(stats.Elapsed.Seconds() / duration.Seconds())
+				}
+			})
+		}
+		generator = g
+
+		startBtn.Disable()
+		stopBtn.Enable()
+		progressBar.SetValue(0)
+		log.Printf("負荷試験を開始 [%s]: %s:%d %s (%.0f msg/s, burst %d)", name, host, port, address, rate, burst)
+
+		go func() {
+			stats, err := g.Run()
+			fyne.Do(func() {
+				if err != nil {
+					log.Printf("負荷試験エラー [%s]: %v", name, err)
+					dialog.ShowError(err, win)
+				}
+				statsLabel.SetText(formatLoadStats(stats))
+				if duration > 0 {
+					progressBar.SetValue(1)
+				}
+				startBtn.Enable()
+				stopBtn.Disable()
+			})
+			log.Printf("負荷試験を終了 [%s]: %s", name, formatLoadStats(stats))
+		}()
+	})
+
+	stopBtn = widget.NewButton("Stop", func() {
+		if generator != nil {
+			generator.Stop()
+		}
+	})
+	stopBtn.Disable()
+
+	form := widget.NewForm(
+		widget.NewFormItem("Target", widget.NewLabel(fmt.Sprintf("%s:%d %s [%s]", host, port, address, formatArguments(arguments)))),
+		widget.NewFormItem("Rate (msg/s)", rateEntry),
+		widget.NewFormItem("Burst size", burstEntry),
+		widget.NewFormItem("Duration (sec)", durationEntry),
+		widget.NewFormItem("", incrementCheck),
+	)
+
+	win.SetContent(container.NewVBox(
+		widget.NewCard("Load Generator", name, nil),
+		form,
+		widget.NewSeparator(),
+		container.NewHBox(startBtn, stopBtn, layout.NewSpacer()),
+		progressBar,
+		statsLabel,
+	))
+	win.Resize(fyne.NewSize(520, 400))
+	win.SetOnClosed(func() {
+		if generator != nil {
+			generator.Stop()
+		}
+	})
+	return win
+}
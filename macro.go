@@ -0,0 +1,486 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// MacroConfig 順番に送るメッセージの並び（マクロ）
+type MacroConfig struct {
+	Name        string      `yaml:"name"`
+	Steps       []MacroStep `yaml:"steps"`
+	StopOnError bool        `yaml:"stop_on_error,omitempty"` // 送信に失敗したら残りのステップを送らない
+}
+
+// MacroStep マクロの1ステップ。delayだけのステップは待つだけ
+type MacroStep struct {
+	Delay     int           `yaml:"delay,omitempty"`  // 送信前に待つ時間（ミリ秒）
+	Target    string        `yaml:"target,omitempty"` // 送信先リストのname（address・argumentsを省略するとその送信先の内容を送る）
+	Host      string        `yaml:"host,omitempty"`   // targetの代わりに直接指定する送信先（targetと併用するとtargetのhostを上書き）
+	Port      int           `yaml:"port,omitempty"`
+	Address   string        `yaml:"address,omitempty"`   // 送るアドレス
+	Arguments []OSCArgument `yaml:"arguments,omitempty"` // 送る引数
+}
+
+// isWait 待つだけのステップかどうか
+func (s MacroStep) isWait() bool {
+	return s.Target == "" && s.Host == "" && s.Port == 0 && s.Address == "" && len(s.Arguments) == 0
+}
+
+// macroAction 送信先を解決したステップ
+type macroAction struct {
+	delay     time.Duration
+	wait      bool
+	label     string // 送信先の表示名（送信先リストのnameまたはhost:port）
+	host      string
+	port      int
+	address   string
+	arguments []OSCArgument
+	// definitions 送信先の引数の設定（送信先の初期値を送る場合のみ。送信時に省略などを反映する）
+	definitions []SenderArgument
+}
+
+// String ステップの内容を表示用の文字列にする
+func (a macroAction) String() string {
+	if a.wait {
+		return fmt.Sprintf("Wait %d ms", a.delay.Milliseconds())
+	}
+	text := fmt.Sprintf("%s → %s", a.label, a.address)
+	if len(a.arguments) > 0 {
+		text += " [" + formatArguments(a.arguments) + "]"
+	}
+	if a.delay > 0 {
+		text = fmt.Sprintf("+%d ms  %s", a.delay.Milliseconds(), text)
+	}
+	return text
+}
+
+// resolveMacroStep ステップの送信先・アドレス・引数を決めて検証する
+func resolveMacroStep(step MacroStep, targets []SenderTarget) (macroAction, error) {
+	action := macroAction{delay: time.Duration(step.Delay) * time.Millisecond}
+	if step.Delay < 0 {
+		return action, fmt.Errorf("delay must be 0 or more: %d", step.Delay)
+	}
+	if step.isWait() {
+		if step.Delay == 0 {
+			return action, errors.New("a step needs a delay or a message to send")
+		}
+		action.wait = true
+		return action, nil
+	}
+
+	action.host, action.port = step.Host, step.Port
+	action.label = fmt.Sprintf("%s:%d", step.Host, step.Port)
+	action.address, action.arguments = step.Address, step.Arguments
+	if step.Target != "" {
+		var target *SenderTarget
+		for i := range targets {
+			if targets[i].Name == step.Target {
+				target = &targets[i]
+				break
+			}
+		}
+		if target == nil {
+			return action, fmt.Errorf("target not found: %s", step.Target)
+		}
+		if step.Host == "" {
+			action.host = target.Host
+		}
+		if step.Port == 0 {
+			action.port = target.Port
+		}
+		action.label = target.Name
+
+		// アドレスを省略したら送信先のアドレスと引数の初期値を送る
+		if step.Address == "" {
+			action.address = target.Address
+			if len(step.Arguments) == 0 {
+				defaults := make([]OSCArgument, 0, len(target.Arguments))
+				for _, arg := range target.Arguments {
+					defaults = append(defaults, OSCArgument{Type: arg.Type, Value: arg.DefaultValue})
+				}
+				action.arguments, action.definitions = defaults, target.Arguments
+			}
+		}
+	}
+
+	if action.host == "" {
+		return action, errors.New("target or host is required")
+	}
+	if action.port <= 0 || action.port > 65535 {
+		return action, fmt.Errorf("invalid port number: %d", action.port)
+	}
+	if !strings.HasPrefix(action.address, "/") {
+		return action, fmt.Errorf("OSC address must start with /: %q", action.address)
+	}
+	// テンプレートを含む引数は書き方だけ確認し、値は送信時に確認する
+	templated := false
+	for _, arg := range action.arguments {
+		if hasTemplate(arg.Value) {
+			if err := checkTemplate(arg.Value, arg.Type); err != nil {
+				return action, err
+			}
+			templated = true
+		}
+	}
+	if !templated {
+		if action.definitions != nil {
+			prepared, err := prepareArguments(action.definitions, action.arguments)
+			if err != nil {
+				return action, err
+			}
+			action.arguments, action.definitions = prepared, nil
+		}
+		if _, err := action.message(nil); err != nil {
+			return action, err
+		}
+	}
+	return action, nil
+}
+
+// message ステップで送るメッセージを作る。templatesがあれば引数のテンプレートを評価する
+func (a macroAction) message(templates *ArgumentTemplates) (*osc.Message, error) {
+	arguments := a.arguments
+	if templates != nil {
+		evaluated, err := templates.Evaluate(arguments)
+		if err != nil {
+			return nil, err
+		}
+		arguments = evaluated
+	}
+	if a.definitions != nil {
+		prepared, err := prepareArguments(a.definitions, arguments)
+		if err != nil {
+			return nil, err
+		}
+		arguments = prepared
+	}
+	return buildOSCMessage(a.address, arguments)
+}
+
+// findMacro 名前でマクロを探す
+func findMacro(macros []MacroConfig, name string) (MacroConfig, bool) {
+	for _, macro := range macros {
+		if macro.Name == name {
+			return macro, true
+		}
+	}
+	return MacroConfig{}, false
+}
+
+// MacroResult 1ステップの実行結果
+type MacroResult struct {
+	Step int // 0始まり
+	Time time.Time
+	Err  error
+}
+
+// MacroRunner マクロのステップを順番に送信する
+type MacroRunner struct {
+	actions     []macroAction
+	stopOnError bool
+
+	// OnStep ステップを始めるとき（待ち時間の前）に呼ばれる
+	OnStep func(step int)
+	// OnResult ステップを終えるたびに呼ばれる
+	OnResult func(result MacroResult)
+	// LastValues 引数の {{last}} テンプレートで使う受信値（nilなら {{last}} は失敗する）
+	LastValues *LastValues
+
+	stopCh  chan struct{}
+	stopped sync.Once
+}
+
+// NewMacroRunner すべてのステップの送信先を解決してMacroRunnerを作成する
+func NewMacroRunner(macro MacroConfig, targets []SenderTarget) (*MacroRunner, error) {
+	if len(macro.Steps) == 0 {
+		return nil, fmt.Errorf("macro %s has no steps", macro.Name)
+	}
+	actions := make([]macroAction, 0, len(macro.Steps))
+	for i, step := range macro.Steps {
+		action, err := resolveMacroStep(step, targets)
+		if err != nil {
+			return nil, fmt.Errorf("Step %d: %w", i+1, err)
+		}
+		actions = append(actions, action)
+	}
+	return &MacroRunner{
+		actions:     actions,
+		stopOnError: macro.StopOnError,
+		stopCh:      make(chan struct{}),
+	}, nil
+}
+
+// Steps ステップの内容を表示用の文字列で返す
+func (r *MacroRunner) Steps() []string {
+	steps := make([]string, len(r.actions))
+	for i, action := range r.actions {
+		steps[i] = action.String()
+	}
+	return steps
+}
+
+// Run ステップを順番に実行する。停止されるか最後まで実行するまで戻らない
+// 失敗したステップがあればエラーを返す（停止された場合はnil）
+func (r *MacroRunner) Run() error {
+	clients := map[string]*osc.Client{}
+	templates := map[int]*ArgumentTemplates{} // ステップごと（{{counter}} は実行するたびに最初から）
+	failed := 0
+	for i, action := range r.actions {
+		if r.OnStep != nil {
+			r.OnStep(i)
+		}
+		if action.delay > 0 {
+			timer := time.NewTimer(action.delay)
+			select {
+			case <-timer.C:
+			case <-r.stopCh:
+				timer.Stop()
+				return nil
+			}
+		} else {
+			select {
+			case <-r.stopCh:
+				return nil
+			default:
+			}
+		}
+
+		result := MacroResult{Step: i, Time: time.Now()}
+		if !action.wait {
+			key := fmt.Sprintf("%s:%d", action.host, action.port)
+			client, ok := clients[key]
+			if !ok {
+				client = osc.NewClient(action.host, action.port)
+				clients[key] = client
+			}
+			if templates[i] == nil {
+				templates[i] = NewArgumentTemplates(r.LastValues)
+			}
+			msg, err := action.message(templates[i])
+			if err == nil {
+				err = client.Send(msg)
+			}
+			result.Err = err
+		}
+		if r.OnResult != nil {
+			r.OnResult(result)
+		}
+
+		if result.Err != nil {
+			failed++
+			if r.stopOnError {
+				return fmt.Errorf("Step %d: %w", i+1, result.Err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d step(s) failed", failed)
+	}
+	return nil
+}
+
+// Stop 実行を停止する
+func (r *MacroRunner) Stop() {
+	r.stopped.Do(func() {
+		close(r.stopCh)
+	})
+}
+
+// createMacroWindow マクロ実行ウィンドウを作成する
+// マクロと送信先は実行するたびにconfigから読む（再読み込み後の内容を使う）
+// 戻り値の関数でマクロの一覧を設定ファイルの内容に合わせて更新する
+func createMacroWindow(a fyne.App, config *AppConfig, lastValues *LastValues, updateHistory func(string)) (fyne.Window, func()) {
+	win := a.NewWindow("Macros")
+
+	// 各ステップの状態表示
+	var steps []string
+	var results []string
+	current := -1
+	stepList := widget.NewList(
+		func() int { return len(steps) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewLabel("  "), nil, widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			row := obj.(*fyne.Container)
+			text := row.Objects[0].(*widget.Label)
+			mark := row.Objects[1].(*widget.Label)
+			text.SetText(fmt.Sprintf("%d. %s  %s", id+1, steps[id], results[id]))
+			switch {
+			case id == current:
+				mark.SetText("▶")
+			case strings.HasPrefix(results[id], "✗"):
+				mark.SetText("✗")
+			case results[id] != "":
+				mark.SetText("✓")
+			default:
+				mark.SetText("  ")
+			}
+		},
+	)
+
+	progressBar := widget.NewProgressBar()
+	statusLabel := widget.NewLabel("Stopped")
+
+	var runner *MacroRunner
+	var runBtn, stopBtn *widget.Button
+
+	// 選択したマクロのステップを表示する
+	macroSelect := widget.NewSelect(nil, nil)
+	showSteps := func(name string) {
+		steps, results, current = nil, nil, -1
+		progressBar.SetValue(0)
+		if macro, ok := findMacro(config.Sender.Macros, name); ok {
+			r, err := NewMacroRunner(macro, config.Sender.List)
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+			} else {
+				steps = r.Steps()
+				statusLabel.SetText("Stopped")
+			}
+		}
+		results = make([]string, len(steps))
+		stepList.Refresh()
+	}
+	macroSelect.OnChanged = showSteps
+
+	// 実行状態に合わせてボタンを切り替える
+	setRunning := func(running bool) {
+		if running {
+			runBtn.Disable()
+			stopBtn.Enable()
+			macroSelect.Disable()
+		} else {
+			runBtn.Enable()
+			stopBtn.Disable()
+			macroSelect.Enable()
+		}
+	}
+
+	runBtn = widget.NewButton("Run", func() {
+		macro, ok := findMacro(config.Sender.Macros, macroSelect.Selected)
+		if !ok {
+			dialog.ShowError(errors.New("select a macro"), win)
+			return
+		}
+		r, err := NewMacroRunner(macro, config.Sender.List)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		r.LastValues = lastValues
+		steps = r.Steps()
+		results = make([]string, len(steps))
+		current = -1
+		stepList.Refresh()
+		progressBar.SetValue(0)
+
+		total := len(steps)
+		r.OnStep = func(step int) {
+			fyne.Do(func() {
+				current = step
+				statusLabel.SetText(fmt.Sprintf("Running: %d / %d", step+1, total))
+				stepList.Refresh()
+				stepList.ScrollTo(step)
+			})
+		}
+		r.OnResult = func(result MacroResult) {
+			timestamp := result.Time.Format("15:04:05.000")
+			text := "✓ " + timestamp
+			if result.Err != nil {
+				log.Printf("マクロ %s のステップ %d の送信に失敗しました: %v", macro.Name, result.Step+1, result.Err)
+				text = fmt.Sprintf("✗ %v", result.Err)
+			}
+			fyne.Do(func() {
+				results[result.Step] = text
+				current = -1
+				progressBar.SetValue(float64(result.Step+1) / float64(total))
+				stepList.Refresh()
+				if result.Err == nil && !r.actions[result.Step].wait {
+					updateHistory(fmt.Sprintf("%s | Macro %s: %s", result.Time.Format("15:04:05"), macro.Name, steps[result.Step]))
+				}
+			})
+		}
+		runner = r
+
+		setRunning(true)
+		log.Printf("マクロ %s を開始 (%dステップ)", macro.Name, total)
+		go func() {
+			err := r.Run()
+			fyne.Do(func() {
+				current = -1
+				stepList.Refresh()
+				if err != nil {
+					log.Printf("マクロ %s: %v", macro.Name, err)
+					statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				} else {
+					statusLabel.SetText("Stopped")
+				}
+				setRunning(false)
+			})
+			log.Printf("マクロ %s を終了", macro.Name)
+		}()
+	})
+
+	stopBtn = widget.NewButton("Stop", func() {
+		if runner != nil {
+			runner.Stop()
+		}
+	})
+	setRunning(false)
+
+	// マクロの一覧を設定に合わせて更新する（実行中は変えない）
+	refresh := func() {
+		if !stopBtn.Disabled() {
+			return
+		}
+		names := make([]string, 0, len(config.Sender.Macros))
+		for _, macro := range config.Sender.Macros {
+			names = append(names, macro.Name)
+		}
+		macroSelect.SetOptions(names)
+		if _, ok := findMacro(config.Sender.Macros, macroSelect.Selected); ok {
+			showSteps(macroSelect.Selected)
+		} else if len(names) > 0 {
+			macroSelect.SetSelected(names[0])
+		} else {
+			macroSelect.ClearSelected()
+			showSteps("")
+		}
+	}
+	refresh()
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewCard("Macros", "Send several messages in order with delays", nil),
+			widget.NewForm(widget.NewFormItem("Macro", macroSelect)),
+			container.NewHBox(runBtn, stopBtn, layout.NewSpacer()),
+			progressBar,
+			statusLabel,
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		stepList,
+	))
+	win.Resize(fyne.NewSize(620, 480))
+	// 閉じても再利用できるよう非表示にする
+	win.SetCloseIntercept(func() {
+		if runner != nil {
+			runner.Stop()
+		}
+		win.Hide()
+	})
+	return win, refresh
+}
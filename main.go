@@ -1,17 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"image/color"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/hypebeast/go-osc/osc"
 	"gopkg.in/yaml.v3"
@@ -19,6 +29,13 @@ import (
 
 // Settings settings.yamlの設定
 type Settings struct {
+	ConfigFile string          `yaml:"config_file"`
+	Profiles   []ConfigProfile `yaml:"profiles,omitempty"` // 画面で切り替えられる設定ファイル
+}
+
+// ConfigProfile 切り替え用の設定ファイル（環境）
+type ConfigProfile struct {
+	Name       string `yaml:"name"`
 	ConfigFile string `yaml:"config_file"`
 }
 
@@ -27,6 +44,8 @@ type AppConfig struct {
 	App      AppSettings      `yaml:"app"`
 	Sender   SenderSettings   `yaml:"sender"`
 	Receiver ReceiverSettings `yaml:"receiver"`
+	Router   RouterSettings   `yaml:"router"`
+	Scripts  []ScriptConfig   `yaml:"scripts,omitempty"`
 }
 
 // AppSettings アプリケーション基本設定
@@ -44,9 +63,18 @@ type WindowSettings struct {
 
 // SenderArgument 送信側の引数定義
 type SenderArgument struct {
-	Type         string `yaml:"type"`
-	DefaultValue string `yaml:"default_value"`
-	Description  string `yaml:"description"`
+	Type         string             `yaml:"type"`
+	DefaultValue string             `yaml:"default_value"`
+	Description  string             `yaml:"description"`
+	Generator    *GeneratorSettings `yaml:"generator,omitempty"` // 数値引数を波形で動かす
+	Widget       string             `yaml:"widget,omitempty"`    // slider, knob, toggle, dropdown, xy
+	Min          float64            `yaml:"min,omitempty"`
+	Max          float64            `yaml:"max,omitempty"`
+	Step         float64            `yaml:"step,omitempty"`
+	Options      []string           `yaml:"options,omitempty"`  // dropdownの選択肢
+	Allowed      []string           `yaml:"allowed,omitempty"`  // 入力できる値
+	Pattern      string             `yaml:"pattern,omitempty"`  // 文字列引数の正規表現
+	Required     *bool              `yaml:"required,omitempty"` // true=空欄不可、false=空欄なら送信しない
 }
 
 // SenderTarget 送信先設定
@@ -56,12 +84,22 @@ type SenderTarget struct {
 	Port      int              `yaml:"port"`
 	Address   string           `yaml:"address"`
 	Arguments []SenderArgument `yaml:"arguments"`
+	Sequence  string           `yaml:"sequence,omitempty"` // "argument"、"address"（アドレス + /seq）または専用アドレス
+	// RepeatInterval 自動送信の間隔（ミリ秒、0=既定の1000ms）
+	RepeatInterval int `yaml:"repeat_interval,omitempty"`
+	// SendOnChange スライダーなどを操作したら送信する
+	SendOnChange bool `yaml:"send_on_change,omitempty"`
+	// SendRate 操作時に送信する最大レート（msg/s、0=既定の30）
+	SendRate float64 `yaml:"send_rate,omitempty"`
+	// Hotkey 送信するキー（例: F1, Q, Ctrl+1。空なら先頭9枚は1〜9、"none"=割り当てない）
+	Hotkey string `yaml:"hotkey,omitempty"`
 }
 
 // SenderSettings 送信側設定
 type SenderSettings struct {
 	List   []SenderTarget `yaml:"list"`
 	Window WindowSettings `yaml:"window"`
+	Macros []MacroConfig  `yaml:"macros,omitempty"` // 順番に送るメッセージの並び
 }
 
 // ReceiverSettings 受信側設定
@@ -69,19 +107,70 @@ type ReceiverSettings struct {
 	DefaultPort   int            `yaml:"default_port"`
 	Window        WindowSettings `yaml:"window"`
 	MaxLogEntries int            `yaml:"max_log_entries"`
+	// WebSocket 受信メッセージをWebSocketで中継するブリッジ
+	WebSocket WebSocketSettings `yaml:"websocket,omitempty"`
+}
+
+// RouteDestination 転送先設定（TargetまたはHost/Portで指定）
+type RouteDestination struct {
+	Target string `yaml:"target,omitempty"` // 送信先リストのname
+	Host   string `yaml:"host,omitempty"`
+	Port   int    `yaml:"port,omitempty"`
+}
+
+// ArgumentScale 数値引数の範囲変換設定
+type ArgumentScale struct {
+	In    [2]float64 `yaml:"in"`              // 入力範囲 [min, max]
+	Out   [2]float64 `yaml:"out"`             // 出力範囲 [min, max]
+	Curve string     `yaml:"curve,omitempty"` // "linear"（デフォルト）または "exp"
+	Clamp bool       `yaml:"clamp,omitempty"` // 入力範囲外の値を範囲内に収める
+}
+
+// ArgumentMapping 書き換え後の引数1つ分の定義
+type ArgumentMapping struct {
+	From  int            `yaml:"from,omitempty"`  // 入力引数の番号（1始まり）
+	Value string         `yaml:"value,omitempty"` // fromの代わりに使う固定値
+	Type  string         `yaml:"type,omitempty"`  // 変換後の型（空=元の型）
+	Scale *ArgumentScale `yaml:"scale,omitempty"`
+}
+
+// RewriteRule 転送時のアドレス・引数の書き換えルール
+type RewriteRule struct {
+	Match     string            `yaml:"match,omitempty"`     // 対象のアドレスパターン（空=ルートのパターン）
+	Address   string            `yaml:"address,omitempty"`   // 書き換え後のアドレス（$1, ${2} でワイルドカード部分を参照）
+	Arguments []ArgumentMapping `yaml:"arguments,omitempty"` // 出力する引数（空=そのまま、記載しない引数は削除）
+}
+
+// RouteConfig 転送ルール設定
+type RouteConfig struct {
+	Name         string             `yaml:"name"`
+	Pattern      string             `yaml:"pattern"` // OSCアドレスパターン（* ? [] {} が使用可能）
+	Disabled     bool               `yaml:"disabled,omitempty"`
+	Destinations []RouteDestination `yaml:"destinations"`
+	Rules        []RewriteRule      `yaml:"rules,omitempty"`
+}
+
+// RouterSettings 転送（ルーター）設定
+type RouterSettings struct {
+	Routes []RouteConfig `yaml:"routes"`
 }
 
 // OSCArgument OSC引数の構造体
 type OSCArgument struct {
-	Type  string // "int", "float", "string", "bool"
-	Value string
+	Type  string `json:"type" yaml:"type"` // "int", "float", "string", "bool" (受信時は "int64", "double", "blob", "timetag", "nil" も)
+	Value string `json:"value" yaml:"value"`
 }
 
 // OSCMessage 受信したOSCメッセージ
 type OSCMessage struct {
-	Timestamp string
-	Address   string
-	Values    string
+	Timestamp   string
+	Address     string
+	Values      string
+	Time        time.Time     // 受信時刻
+	Source      string        // 送信元アドレス (host:port)
+	Destination string        // 宛先アドレス (host:port)
+	Arguments   []OSCArgument // 型付きの引数
+	Packet      []byte        // 受信したUDPペイロードそのまま（同じバンドルのメッセージは共有する）
 }
 
 // LoadSettings settings.yamlを読み込む
@@ -126,8 +215,12 @@ func LoadConfig(filename string) (*AppConfig, error) {
 		return nil, err
 	}
 
-	// YAMLをパース
-	err = yaml.Unmarshal(data, config)
+	// includeと変数を展開してからパース
+	doc := loadConfigDocument(filename, data)
+	if len(doc.issues) > 0 {
+		return nil, &ConfigError{Filename: filename, Issues: doc.issues}
+	}
+	err = doc.root.Decode(config)
 	if err != nil {
 		return nil, err
 	}
@@ -178,7 +271,16 @@ func getDefaultConfig() *AppConfig {
 }
 
 // createSenderSection 単一の送信セクションを作成
-func createSenderSection(target SenderTarget, index int, updateHistory func(string)) *widget.Card {
+func createSenderSection(parent fyne.Window, target SenderTarget, actions senderSectionActions, lastValues *LastValues, updateHistory func(string), openLoadTest func(name, host string, port int, address string, arguments []OSCArgument)) (*widget.Card, *senderSectionState) {
+	// 未保存の変更の表示
+	dirtyLabel := widget.NewLabel("● Unsaved")
+	dirtyLabel.Importance = widget.WarningImportance
+	dirtyLabel.Hide()
+	state := &senderSectionState{dirtyLabel: dirtyLabel, onChange: actions.Changed}
+
+	// 引数のテンプレート（{{counter}} などのカウンターはカードごと）
+	templates := NewArgumentTemplates(lastValues)
+
 	// OSC送信用のUI要素（固定サイズコンテナでラップ）
 	hostEntry := widget.NewEntry()
 	hostEntry.SetText(target.Host)
@@ -204,36 +306,104 @@ func createSenderSection(target SenderTarget, index int, updateHistory func(stri
 	addressContainer.Resize(fyne.NewSize(200, 32))
 	addressEntry.Move(fyne.NewPos(0, 0))
 
+	// 編集されたら未保存の表示を更新する
+	hostEntry.OnChanged = func(string) { state.Refresh() }
+	portEntry.OnChanged = func(string) { state.Refresh() }
+	addressEntry.OnChanged = func(string) { state.Refresh() }
+
 	// 設定ファイルから引数の初期値を読み込み
 	var arguments []OSCArgument
-	for _, argDef := range target.Arguments {
+	var definitions []SenderArgument // 引数ごとの設定（説明・操作用ウィジェット）
+	var generators []*Generator      // 引数ごとの波形（nil=固定値）
+	for i, argDef := range target.Arguments {
 		arguments = append(arguments, OSCArgument{
 			Type:  argDef.Type,
 			Value: argDef.DefaultValue,
 		})
+
+		var next *SenderArgument
+		if i+1 < len(target.Arguments) {
+			next = &target.Arguments[i+1]
+		}
+		if err := argDef.validateRules(); err != nil {
+			log.Printf("入力ルールの設定が無効です [%s] Arg%d: %v", target.Name, i+1, err)
+		} else if err := argDef.validateControl(next); err != nil {
+			// 無効なウィジェットは表示しない（activeControlで除外される）
+			log.Printf("ウィジェットの設定が無効です [%s] Arg%d: %v", target.Name, i+1, err)
+		}
+		definitions = append(definitions, argDef)
+
+		var generator *Generator
+		if argDef.Generator != nil {
+			g, err := NewGenerator(*argDef.Generator)
+			if err != nil {
+				log.Printf("波形の設定が無効です [%s]: %v", target.Name, err)
+			} else if !isNumericArgumentType(argDef.Type) {
+				log.Printf("波形は数値引数にのみ設定できます [%s]: %s", target.Name, argDef.Type)
+			} else {
+				generator = g
+			}
+		}
+		generators = append(generators, generator)
 	}
+	valueEntries := make([]*widget.Entry, len(arguments))
 
 	argumentsContainer := container.NewVBox()
 
+	// 操作用ウィジェットで値が変わったときの処理（送信処理の作成後に設定する）
+	var notifyChange func()
+
+	// activeControl 現在の引数タイプで使える操作用ウィジェットの設定を返す
+	activeControl := func(i int) SenderArgument {
+		def := definitions[i]
+		def.Type = arguments[i].Type
+		var next *SenderArgument
+		if i+1 < len(arguments) {
+			nextDef := definitions[i+1]
+			nextDef.Type = arguments[i+1].Type
+			next = &nextDef
+		}
+		if def.validateRules() != nil || def.validateControl(next) != nil {
+			def.Widget = ""
+		}
+		return def
+	}
+
 	// 引数表示を更新する関数
 	var updateArgumentsDisplay func()
 	updateArgumentsDisplay = func() {
 		argumentsContainer.RemoveAll()
+		valueEntries = make([]*widget.Entry, len(arguments))
 		for j, arg := range arguments {
 			argIndex := j // クロージャ用
 
 			// 引数の説明を取得
-			description := ""
-			if argIndex < len(target.Arguments) {
-				description = target.Arguments[argIndex].Description
-			}
+			description := definitions[argIndex].Description
+			control := activeControl(argIndex)
+			isXYTarget := argIndex > 0 && activeControl(argIndex-1).Widget == controlXY
 
 			// 引数タイプ選択
 			// argIndexをキャプチャしてクロージャ問題を回避
 			capturedIndex := argIndex
-			typeSelect := widget.NewSelect([]string{"int", "float", "string", "bool"}, func(value string) {
+			typeSelect := widget.NewSelect(senderArgumentTypes, func(value string) {
 				if capturedIndex < len(arguments) {
+					changed := arguments[capturedIndex].Type != value
 					arguments[capturedIndex].Type = value
+					state.Refresh()
+					// 新しいタイプで入力値を検証し直す
+					if valueEntries[capturedIndex] != nil {
+						valueEntries[capturedIndex].Validate()
+					}
+					// 数値以外に変えたら波形を外す
+					if generators[capturedIndex] != nil && !isNumericArgumentType(value) {
+						generators[capturedIndex] = nil
+						updateArgumentsDisplay()
+						return
+					}
+					// 操作用ウィジェットは新しいタイプで作り直す
+					if changed && (definitions[capturedIndex].Widget != "" || isXYTarget) {
+						updateArgumentsDisplay()
+					}
 				}
 			})
 			typeSelect.SetSelected(arg.Type)
@@ -248,14 +418,65 @@ func createSenderSection(target SenderTarget, index int, updateHistory func(stri
 			valueEntry.OnChanged = func(value string) {
 				if valueEntryIndex < len(arguments) {
 					arguments[valueEntryIndex].Value = value
+					state.Refresh()
+				}
+			}
+			valueEntries[argIndex] = valueEntry
+
+			// 入力中に検証し、エラーは欄を赤くして下に表示する
+			errorLabel := widget.NewLabel("")
+			errorLabel.Importance = widget.DangerImportance
+			errorLabel.Hide()
+			valueEntry.Validator = func(value string) error {
+				if valueEntryIndex >= len(arguments) {
+					return nil
+				}
+				// テンプレートは送信時に評価するので書き方だけ確認する
+				if hasTemplate(value) {
+					return checkTemplate(value, arguments[valueEntryIndex].Type)
+				}
+				return definitions[valueEntryIndex].validateValue(OSCArgument{Type: arguments[valueEntryIndex].Type, Value: value})
+			}
+			valueEntry.SetOnValidationChanged(func(err error) {
+				if err != nil {
+					errorLabel.SetText(err.Error())
+					errorLabel.Show()
+				} else {
+					errorLabel.Hide()
 				}
+			})
+			valueEntry.Validate()
+
+			// 波形ボタン（設定中は波形名を表示し、値は送信時に計算する）
+			generatorIndex := argIndex
+			generatorLabel := "∿"
+			if generators[argIndex] != nil {
+				generatorLabel = generators[argIndex].Settings().Shape
+				valueEntry.Disable()
 			}
+			generatorBtn := widget.NewButton(generatorLabel, func() {
+				if generatorIndex >= len(arguments) {
+					return
+				}
+				if !isNumericArgumentType(arguments[generatorIndex].Type) {
+					dialog.ShowError(fmt.Errorf("waveforms can only be set on numeric arguments: %s", arguments[generatorIndex].Type), parent)
+					return
+				}
+				showGeneratorDialog(parent, generators[generatorIndex], func(g *Generator) {
+					generators[generatorIndex] = g
+					updateArgumentsDisplay()
+				})
+			})
 
 			// 削除ボタン
 			removeBtnIndex := argIndex
 			removeBtn := widget.NewButton("✕", func() {
 				if removeBtnIndex < len(arguments) {
 					arguments = append(arguments[:removeBtnIndex], arguments[removeBtnIndex+1:]...)
+					definitions = append(definitions[:removeBtnIndex], definitions[removeBtnIndex+1:]...)
+					generators = append(generators[:removeBtnIndex], generators[removeBtnIndex+1:]...)
+					// {{counter}} は引数の番号ごとに数えるので、番号がずれたら最初から数え直す
+					templates.Reset()
 					updateArgumentsDisplay()
 				}
 			})
@@ -265,6 +486,42 @@ func createSenderSection(target SenderTarget, index int, updateHistory func(stri
 			if description != "" {
 				labelText += fmt.Sprintf(" (%s)", description)
 			}
+			if isXYTarget {
+				labelText += " [Y]"
+			}
+
+			// 操作用ウィジェット（値の入力欄と連動し、操作したら送信できる）
+			center := fyne.CanvasObject(valueEntry)
+			var padArea fyne.CanvasObject
+			if generators[argIndex] == nil {
+				controlIndex := argIndex
+				onControlChange := func(value string) {
+					valueEntries[controlIndex].SetText(value)
+					if notifyChange != nil {
+						notifyChange()
+					}
+				}
+				if obj := newArgumentControl(control, arg.Type, arg.Value, onControlChange); obj != nil {
+					center = container.NewGridWithColumns(2, obj, valueEntry)
+				} else if control.Widget == controlXY {
+					yDef := definitions[argIndex+1]
+					yDef.Type = arguments[argIndex+1].Type
+					xLow, xHigh := control.controlRange()
+					yLow, yHigh := yDef.controlRange()
+					pad := NewXYPad(
+						(control.controlValue(arg.Value)-xLow)/(xHigh-xLow),
+						(yDef.controlValue(arguments[argIndex+1].Value)-yLow)/(yHigh-yLow),
+						func(x, y float64) {
+							valueEntries[controlIndex].SetText(formatControlValue(control.Type, control.quantize(xLow+x*(xHigh-xLow))))
+							valueEntries[controlIndex+1].SetText(formatControlValue(yDef.Type, yDef.quantize(yLow+y*(yHigh-yLow))))
+							if notifyChange != nil {
+								notifyChange()
+							}
+						},
+					)
+					padArea = container.NewHBox(container.NewGridWrap(fyne.NewSize(200, 200), pad))
+				}
+			}
 
 			// 要素間のスペースを確保するため、BorderLayoutを使用
 			leftContent := container.NewHBox(
@@ -274,12 +531,17 @@ func createSenderSection(target SenderTarget, index int, updateHistory func(stri
 
 			argRow := container.NewBorder(
 				nil, nil, // top, bottom
-				leftContent, removeBtn, // left, right
-				valueEntry, // center
+				leftContent, container.NewHBox(generatorBtn, removeBtn), // left, right
+				center, // center
 			)
 			argumentsContainer.Add(argRow)
+			argumentsContainer.Add(errorLabel)
+			if padArea != nil {
+				argumentsContainer.Add(padArea)
+			}
 		}
 		argumentsContainer.Refresh()
+		state.Refresh()
 	}
 
 	// 初期表示
@@ -288,99 +550,447 @@ func createSenderSection(target SenderTarget, index int, updateHistory func(stri
 	// 引数追加ボタン
 	addArgBtn := widget.NewButton("＋", func() {
 		arguments = append(arguments, OSCArgument{Type: "int", Value: "0"})
+		definitions = append(definitions, SenderArgument{})
+		generators = append(generators, nil)
+		templates.Reset()
 		updateArgumentsDisplay()
 	})
 
-	// 送信ボタン
-	sendBtn := widget.NewButton("Send", func() {
+	// 送信できない理由をログとカードに表示する関数
+	// 操作時の送信ではキー入力のたびに呼ばれるので、ダイアログではなくカード内に表示する
+	sendErrorLabel := widget.NewLabel("")
+	sendErrorLabel.Importance = widget.DangerImportance
+	sendErrorLabel.Wrapping = fyne.TextWrapWord
+	sendErrorLabel.Hide()
+	showSendError := func(err error) {
+		log.Printf("送信エラー [%s]: %v", target.Name, err)
+		sendErrorLabel.SetText(fmt.Sprintf("Not sent: %v", err))
+		sendErrorLabel.Show()
+	}
+
+	// 入力中の送信先を取得する関数
+	readDestination := func() (string, int, string, error) {
 		host := hostEntry.Text
 		portStr := portEntry.Text
 		address := addressEntry.Text
 
 		if host == "" || portStr == "" || address == "" {
-			log.Printf("送信エラー [%s]: ホスト、ポート、アドレスを入力してください", target.Name)
-			return
+			return "", 0, "", errors.New("enter a host, port and address")
 		}
 
 		// ポート番号をパース
-		port, err := strconv.Atoi(portStr)
+		port, err := parsePort(portStr)
 		if err != nil {
-			log.Printf("ポート番号が無効です [%s]: %s", target.Name, portStr)
-			return
+			return "", 0, "", err
+		}
+		return host, port, address, nil
+	}
+	portEntry.Validator = func(value string) error {
+		_, err := parsePort(value)
+		return err
+	}
+
+	// シーケンス番号（受信側で欠落・順序の入れ替わりを検出するため）
+	var sequence int64
+	sequenceMode, sequenceConfigured := sequenceModeFromConfig(target.Sequence)
+	sequenceSelect := widget.NewSelect(sequenceModes, func(value string) {
+		// 切り替えたら0から数え直す
+		sequenceMode = value
+		sequence = 0
+		state.Refresh()
+	})
+	sequenceSelect.SetSelected(sequenceMode)
+
+	// 現在の入力内容で1回送信する関数（UIスレッドから呼ぶ）
+	send := func() bool {
+		host, port, address, err := readDestination()
+		if err != nil {
+			showSendError(err)
+			return false
 		}
 
 		// OSCクライアントを作成
 		client := osc.NewClient(host, port)
 
-		// OSCメッセージを作成
-		msg := osc.NewMessage(address)
-
-		// 引数を追加
-		for _, arg := range arguments {
-			switch arg.Type {
-			case "int":
-				if val, err := strconv.Atoi(arg.Value); err == nil {
-					msg.Append(int32(val))
-				} else {
-					log.Printf("int変換エラー [%s]: %s", target.Name, arg.Value)
-					return
-				}
-			case "float":
-				if val, err := strconv.ParseFloat(arg.Value, 32); err == nil {
-					msg.Append(float32(val))
-				} else {
-					log.Printf("float変換エラー [%s]: %s", target.Name, arg.Value)
-					return
-				}
-			case "string":
-				msg.Append(arg.Value)
-			case "bool":
-				if val, err := strconv.ParseBool(arg.Value); err == nil {
-					msg.Append(val)
-				} else {
-					log.Printf("bool変換エラー [%s]: %s", target.Name, arg.Value)
-					return
-				}
+		// 波形が設定された引数は現在の値を計算して表示にも反映する
+		now := time.Now()
+		for i, g := range generators {
+			if g != nil {
+				arguments[i] = g.Argument(arguments[i].Type, now)
+				valueEntries[i].SetText(arguments[i].Value)
 			}
 		}
 
+		// テンプレートを評価し（入力欄はそのまま）、引数を検証して送信用の引数を作る
+		evaluated, err := templates.Evaluate(arguments)
+		if err != nil {
+			showSendError(err)
+			return false
+		}
+		sendArguments, err := prepareArguments(definitions, evaluated)
+		if err != nil {
+			showSendError(err)
+			return false
+		}
+
+		// OSCメッセージを作成
+		if sequenceMode == sequenceArgument {
+			sendArguments = append(sendArguments, OSCArgument{Type: "int", Value: strconv.FormatInt(sequence, 10)})
+		}
+		msg, err := buildOSCMessage(address, sendArguments)
+		if err != nil {
+			showSendError(err)
+			return false
+		}
+
 		// OSCメッセージを送信
 		err = client.Send(msg)
 		if err != nil {
-			log.Printf("OSC送信エラー [%s]: %v", target.Name, err)
-			return
+			showSendError(err)
+			return false
+		}
+
+		// 専用アドレスの場合は続けて番号だけを送る
+		sequenceAddr := sequenceAddressFor(sequenceConfigured, address)
+		if sequenceMode == sequenceAddress {
+			if err := client.Send(osc.NewMessage(sequenceAddr, int32(sequence))); err != nil {
+				showSendError(err)
+				return false
+			}
 		}
 
 		// 引数の情報をログ出力
-		var argInfo []string
-		for _, arg := range arguments {
-			argInfo = append(argInfo, fmt.Sprintf("%s:%s", arg.Type, arg.Value))
+		argInfo := formatArguments(sendArguments)
+		if sequenceMode == sequenceAddress {
+			argInfo += fmt.Sprintf(" %s #%d", sequenceAddr, sequence)
+		}
+		if sequenceMode != sequenceOff {
+			sequence++
 		}
 
-		logMsg := fmt.Sprintf("OSC送信完了 [%s]: %s:%d %s [%s]", target.Name, host, port, address, strings.Join(argInfo, ", "))
+		logMsg := fmt.Sprintf("OSC送信完了 [%s]: %s:%d %s [%s]", target.Name, host, port, address, argInfo)
 		log.Printf("%s", logMsg)
 
 		// 送信履歴を更新
 		timestamp := time.Now().Format("15:04:05")
-		historyMsg := fmt.Sprintf("%s | %s → %s:%d %s [%s]", timestamp, target.Name, host, port, address, strings.Join(argInfo, ", "))
+		historyMsg := fmt.Sprintf("%s | %s → %s:%d %s [%s]", timestamp, target.Name, host, port, address, argInfo)
 		updateHistory(historyMsg)
+		sendErrorLabel.Hide()
+		return true
+	}
+
+	// 送信ボタン
+	sendBtn := widget.NewButton("Send", func() {
+		send()
 	})
 
 	// Sendボタンのサイズを大きく設定
 	sendBtn.Resize(fyne.NewSize(80, 40))
 
+	// 負荷試験ボタン（現在の入力内容で負荷試験ウィンドウを開く）
+	loadBtn := widget.NewButton("Load...", func() {
+		host, port, address, err := readDestination()
+		if err != nil {
+			showSendError(err)
+			return
+		}
+		// テンプレートは開いた時点の値で固定する
+		evaluated, err := templates.Evaluate(arguments)
+		if err != nil {
+			showSendError(err)
+			return
+		}
+		loadArguments, err := prepareArguments(definitions, evaluated)
+		if err != nil {
+			showSendError(err)
+			return
+		}
+		openLoadTest(target.Name, host, port, address, loadArguments)
+	})
+
+	// 操作したら送信する（最大レートで間引き、最後の値は必ず送る）
+	sendRate := target.SendRate
+	if sendRate <= 0 {
+		sendRate = 30
+	}
+	sendOnChangeCheck := widget.NewCheck("Send on change", func(bool) { state.Refresh() })
+	sendOnChangeCheck.SetChecked(target.SendOnChange)
+	var lastChangeSend time.Time
+	var changePending bool
+	notifyChange = func() {
+		if !sendOnChangeCheck.Checked {
+			return
+		}
+		interval := time.Duration(float64(time.Second) / sendRate)
+		if wait := interval - time.Since(lastChangeSend); wait > 0 {
+			if !changePending {
+				changePending = true
+				time.AfterFunc(wait, func() {
+					fyne.Do(func() {
+						changePending = false
+						lastChangeSend = time.Now()
+						send()
+					})
+				})
+			}
+			return
+		}
+		lastChangeSend = time.Now()
+		send()
+	}
+
+	// 自動送信（キープアライブや連続値の送信用）
+	repeatInterval := target.RepeatInterval
+	if repeatInterval <= 0 {
+		repeatInterval = 1000
+	}
+	repeatEntry := widget.NewEntry()
+	repeatEntry.SetText(strconv.Itoa(repeatInterval))
+	repeatEntry.SetPlaceHolder("ms")
+	repeatEntry.Resize(fyne.NewSize(80, 32))
+	repeatContainer := container.NewWithoutLayout(repeatEntry)
+	repeatContainer.Resize(fyne.NewSize(80, 32))
+	repeatEntry.Move(fyne.NewPos(0, 0))
+	repeatEntry.OnChanged = func(string) { state.Refresh() }
+
+	repeatStatus := widget.NewLabel("")
+	var repeatStop chan struct{}
+	var repeatCount int
+
+	// stopRepeat 自動送信を停止する
+	stopRepeat := func() {
+		if repeatStop == nil {
+			return
+		}
+		close(repeatStop)
+		repeatStop = nil
+		repeatEntry.Enable()
+		repeatStatus.SetText(fmt.Sprintf("Stopped (%d sent)", repeatCount))
+		repeatStatus.Importance = widget.MediumImportance
+		repeatStatus.Refresh()
+		log.Printf("自動送信を停止 [%s]: %d件", target.Name, repeatCount)
+	}
+
+	var repeatCheck *widget.Check
+	repeatCheck = widget.NewCheck("Repeat", func(checked bool) {
+		if !checked {
+			stopRepeat()
+			return
+		}
+		if repeatStop != nil {
+			return
+		}
+
+		interval, err := strconv.Atoi(repeatEntry.Text)
+		if err != nil || interval <= 0 {
+			log.Printf("自動送信の間隔が無効です [%s]: %s", target.Name, repeatEntry.Text)
+			repeatCheck.SetChecked(false)
+			return
+		}
+
+		stop := make(chan struct{})
+		repeatStop = stop
+		repeatCount = 0
+		// 波形は自動送信の開始位置から動かす
+		for _, g := range generators {
+			if g != nil {
+				g.Reset(time.Now())
+			}
+		}
+		repeatEntry.Disable()
+		repeatStatus.SetText("● Running (0 sent)")
+		repeatStatus.Importance = widget.SuccessImportance
+		repeatStatus.Refresh()
+		log.Printf("自動送信を開始 [%s]: %dms間隔", target.Name, interval)
+
+		go func() {
+			ticker := time.NewTicker(time.Duration(interval) * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+				fyne.Do(func() {
+					// 停止済みのtickは無視する
+					if repeatStop != stop {
+						return
+					}
+					if !send() {
+						// 送信できない状態なら止める
+						repeatCheck.SetChecked(false)
+						return
+					}
+					repeatCount++
+					repeatStatus.SetText(fmt.Sprintf("● Running (%d sent)", repeatCount))
+				})
+			}
+		}()
+	})
+
 	// nameラベルを大きなフォントで作成
 	nameLabel := widget.NewRichTextFromMarkdown(fmt.Sprintf("## %s", target.Name))
 	nameLabel.Wrapping = fyne.TextWrapOff
 
+	// 割り当てられたホットキーの表示
+	hotkeyLabel := widget.NewLabel("")
+	hotkeyLabel.Importance = widget.LowImportance
+	hotkeyLabel.Hide()
+	state.hotkeyLabel = hotkeyLabel
+
+	// ホットキーで送信したときにカードを光らせる（失敗したら赤）
+	flashRect := canvas.NewRectangle(color.Transparent)
+	var flashAnim *fyne.Animation
+	state.fire = func() bool {
+		sent := send()
+		flashColor := theme.Color(theme.ColorNamePrimary)
+		if !sent {
+			flashColor = theme.Color(theme.ColorNameError)
+		}
+		r, g, b, _ := flashColor.RGBA()
+		start := color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 0x80}
+		end := start
+		end.A = 0
+		if flashAnim != nil {
+			flashAnim.Stop()
+		}
+		flashAnim = canvas.NewColorRGBAAnimation(start, end, 400*time.Millisecond, func(c color.Color) {
+			flashRect.FillColor = c
+			flashRect.Refresh()
+		})
+		flashAnim.Start()
+		return sent
+	}
+
+	// 削除時は自動送信を止める
+	state.onRemove = func() {
+		repeatCheck.SetChecked(false)
+	}
+
+	// カードのメニュー（名前の変更・ホットキー・カウンターのリセット・複製・並べ替え・削除）
+	var menuBtn *widget.Button
+	menuBtn = widget.NewButton("⋯", func() {
+		menu := fyne.NewMenu("",
+			fyne.NewMenuItem("Rename...", func() {
+				nameEntry := widget.NewEntry()
+				nameEntry.SetText(target.Name)
+				// 名前は設定ファイルのマージや転送・マクロ・WebSocketの参照に使うので重複させない
+				nameEntry.Validator = func(text string) error {
+					name := strings.TrimSpace(text)
+					if name == "" {
+						return errors.New("enter a name")
+					}
+					if name != target.Name && actions.NameInUse != nil && actions.NameInUse(state, name) {
+						return fmt.Errorf("another target is already named %q", name)
+					}
+					return nil
+				}
+				dialog.ShowForm("Rename Target", "OK", "Cancel", []*widget.FormItem{
+					widget.NewFormItem("Name", nameEntry),
+				}, func(ok bool) {
+					name := strings.TrimSpace(nameEntry.Text)
+					if !ok || name == target.Name || nameEntry.Validate() != nil {
+						return
+					}
+					rename := func() {
+						oldName := target.Name
+						log.Printf("送信先の名前を変更: %s → %s", oldName, name)
+						target.Name = name
+						nameLabel.ParseMarkdown(fmt.Sprintf("## %s", name))
+						state.Refresh()
+						if actions.Renamed != nil {
+							actions.Renamed(oldName, name)
+						}
+					}
+					var scripts []string
+					if actions.ScriptsReferencing != nil {
+						scripts = actions.ScriptsReferencing(target.Name)
+					}
+					if len(scripts) == 0 {
+						rename()
+						return
+					}
+					// スクリプトの中の名前は書き換えられないので、変えてよいか確認する
+					dialog.ShowConfirm("Rename Target",
+						fmt.Sprintf("These scripts refer to %q and will not be updated:\n%s\n\nRename anyway?", target.Name, strings.Join(scripts, "\n")),
+						func(ok bool) {
+							if ok {
+								rename()
+							}
+						}, parent)
+				}, parent)
+			}),
+			fyne.NewMenuItem("Hotkey...", func() {
+				hotkeyEntry := widget.NewEntry()
+				hotkeyEntry.SetText(target.Hotkey)
+				hotkeyEntry.SetPlaceHolder("F1, Q, Ctrl+1, none (empty = 1-9 by position)")
+				hotkeyEntry.Validator = func(text string) error {
+					text = strings.TrimSpace(text)
+					if text == "" || strings.EqualFold(text, hotkeyNone) {
+						return nil
+					}
+					h, err := parseHotkey(text)
+					if err != nil {
+						return err
+					}
+					if actions.HotkeyInUse != nil {
+						if name, ok := actions.HotkeyInUse(state, h); ok {
+							return fmt.Errorf("%s is already used by %q", h, name)
+						}
+					}
+					return nil
+				}
+				dialog.ShowForm("Target Hotkey", "OK", "Cancel", []*widget.FormItem{
+					widget.NewFormItem("Hotkey", hotkeyEntry),
+				}, func(ok bool) {
+					hotkey := strings.TrimSpace(hotkeyEntry.Text)
+					if !ok || hotkey == target.Hotkey {
+						return
+					}
+					log.Printf("送信先のホットキーを変更 [%s]: %q → %q", target.Name, target.Hotkey, hotkey)
+					target.Hotkey = hotkey
+					state.Refresh()
+				}, parent)
+			}),
+			fyne.NewMenuItem("Reset Counters", func() {
+				templates.Reset()
+				log.Printf("送信先のカウンターをリセット [%s]", target.Name)
+			}),
+			fyne.NewMenuItem("Duplicate", func() {
+				actions.Duplicate(state)
+			}),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Move Up", func() {
+				actions.Move(state, -1)
+			}),
+			fyne.NewMenuItem("Move Down", func() {
+				actions.Move(state, 1)
+			}),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Delete...", func() {
+				actions.Delete(state)
+			}),
+		)
+		canvas := fyne.CurrentApp().Driver().CanvasForObject(menuBtn)
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(menuBtn)
+		widget.ShowPopUpMenuAtPosition(menu, canvas, pos.Add(fyne.NewPos(0, menuBtn.Size().Height)))
+	})
+
 	// セクションのレイアウト
 	sectionContent := container.NewVBox(
 		// nameとSendボタンを横並び
 		container.NewHBox(
 			sendBtn,
 			nameLabel,
+			hotkeyLabel,
+			dirtyLabel,
 			layout.NewSpacer(),
+			sequenceSelect,
+			loadBtn,
+			menuBtn,
 		),
+		sendErrorLabel,
 
 		widget.NewSeparator(),
 
@@ -444,28 +1054,94 @@ func createSenderSection(target SenderTarget, index int, updateHistory func(stri
 			return layoutContainer
 		}(),
 
+		// 自動送信
+		container.NewHBox(
+			repeatCheck,
+			widget.NewLabel("Interval (ms):"),
+			repeatContainer,
+			layout.NewSpacer(),
+			repeatStatus,
+		),
+
 		widget.NewSeparator(),
 
 		// 引数設定
 		container.NewHBox(
 			addArgBtn,
+			layout.NewSpacer(),
+			sendOnChangeCheck,
 		),
 
 		argumentsContainer,
 	)
 
+	// 入力中の内容をSenderTargetにする関数
+	state.current = func() (SenderTarget, error) {
+		current := target
+		current.Host = hostEntry.Text
+		current.Address = addressEntry.Text
+		port, err := parsePort(portEntry.Text)
+		if err != nil {
+			return current, err
+		}
+		current.Port = port
+
+		current.Arguments = make([]SenderArgument, len(arguments))
+		for i, arg := range arguments {
+			def := definitions[i]
+			def.Type = arg.Type
+			def.Generator = nil
+			if generators[i] != nil {
+				// 波形の値は送信のたびに変わるので初期値は変えない
+				settings := generators[i].Settings()
+				def.Generator = &settings
+			} else {
+				def.DefaultValue = arg.Value
+			}
+			current.Arguments[i] = def
+		}
+
+		current.Sequence = sequenceConfigValue(sequenceMode, target.Sequence)
+		current.SendOnChange = sendOnChangeCheck.Checked
+		if interval, err := strconv.Atoi(repeatEntry.Text); err == nil && interval > 0 {
+			current.RepeatInterval = interval
+			if target.RepeatInterval == 0 && interval == 1000 {
+				current.RepeatInterval = 0 // 既定値のままなら書き出さない
+			}
+		}
+		return current, nil
+	}
+	state.MarkSaved()
+
 	return widget.NewCard(
 		"",
 		"",
-		sectionContent,
-	)
+		container.NewStack(flashRect, sectionContent),
+	), state
 }
 
 func main() {
-	// settings.yamlを読み込み
-	settings, err := LoadSettings("settings/settings.yaml")
+	// サブコマンドが指定されていればGUIを起動せずに実行
+	if handled, err := runCommand(os.Args[1:]); handled {
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	// コマンドラインのフラグと環境変数を読み取る
+	opts, err := parseLaunchOptions(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("settings.yamlの読み込みに失敗しました: %v", err)
+		log.Fatalf("%v", err)
+	}
+
+	// settings.yamlを読み込み、使用する設定ファイルを決める
+	settings, err := resolveSettings(opts.SettingsFile, opts.ConfigFile)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
 	// settings.yamlで指定されたconfigファイルを読み込み
@@ -474,6 +1150,15 @@ func main() {
 		log.Fatalf("設定ファイル %s の読み込みに失敗しました: %v", settings.ConfigFile, err)
 	}
 
+	// 設定ファイルの内容を検証する（問題があっても起動し、画面に表示する）
+	configCheckErr := ValidateConfigFile(settings.ConfigFile)
+	if os.IsNotExist(configCheckErr) {
+		configCheckErr = nil
+	}
+	if configCheckErr != nil {
+		log.Printf("設定ファイルに問題があります:\n%v", configCheckErr)
+	}
+
 	a := app.NewWithID("com.example.gooscchecker")
 
 	// メッセージ管理用のスライス
@@ -507,23 +1192,500 @@ func main() {
 		historyLabel.SetText(historyText)
 	}
 
+	// 負荷試験ウィンドウを開く関数
+	openLoadTest := func(name, host string, port int, address string, arguments []OSCArgument) {
+		createLoadWindow(a, name, host, port, address, arguments).Show()
+	}
+
 	// 複数送信セクションを格納するコンテナ
 	sendersContainer := container.NewVBox()
 
+	// 送信先カードとその編集状態（表示順）
+	var senderCards []*widget.Card
+	var senderStates []*senderSectionState
+
+	// 最後に受信した値（送信の {{last}} テンプレート用）
+	lastValues := NewLastValues()
+
+	// 送受信を行うスクリプト（設定ファイルの scripts）
+	scriptEngine := NewScriptEngine(lastValues)
+	scriptEngine.OnSend = func(script, text string) {
+		timestamp := time.Now().Format("15:04:05")
+		fyne.Do(func() {
+			updateSendHistory(fmt.Sprintf("%s | Script %s: %s", timestamp, script, text))
+		})
+	}
+
+	// 受信メッセージの転送ルール
+	router := NewRouter(config.Router, config.Sender.List)
+
+	// 受信メッセージをWebSocketのクライアントに中継し、クライアントからの送信を受け付ける
+	bridge := NewWebSocketBridge(lastValues)
+	bridge.SendTarget = func(name string) error {
+		err := fmt.Errorf("target not found: %s", name)
+		fyne.DoAndWait(func() {
+			for _, state := range senderStates {
+				if target, _ := state.Target(); target.Name == name {
+					err = nil
+					if !state.Fire() {
+						err = fmt.Errorf("could not send %s", name)
+					}
+					return
+				}
+			}
+		})
+		return err
+	}
+	bridge.Targets = func() []SenderTarget {
+		var targets []SenderTarget
+		fyne.DoAndWait(func() {
+			targets = append(targets, config.Sender.List...)
+		})
+		return targets
+	}
+	bridge.OnSend = func(text string) {
+		timestamp := time.Now().Format("15:04:05")
+		fyne.Do(func() {
+			updateSendHistory(fmt.Sprintf("%s | %s", timestamp, text))
+		})
+	}
+
+	// 送信先の追加・削除・並べ替えが未保存であることの表示
+	listDirty := false
+	listDirtyLabel := widget.NewLabel("● Unsaved")
+	listDirtyLabel.Importance = widget.WarningImportance
+	listDirtyLabel.Hide()
+	setListDirty := func(dirty bool) {
+		listDirty = dirty
+		if dirty {
+			listDirtyLabel.Show()
+		} else {
+			listDirtyLabel.Hide()
+		}
+	}
+
+	// カードを表示順に並べ直す関数
+	updateSendersDisplay := func() {
+		sendersContainer.RemoveAll()
+		for i, card := range senderCards {
+			sendersContainer.Add(card)
+
+			// 最後以外はセパレータを追加
+			if i < len(senderCards)-1 {
+				sendersContainer.Add(widget.NewSeparator())
+			}
+		}
+		sendersContainer.Refresh()
+	}
+
+	// ホットキーをカードに割り当てる関数（カードの並びやhotkeyが変わったときだけ登録し直す）
+	hotkeyStates := map[Hotkey]*senderSectionState{}
+	var hotkeyShortcuts []fyne.Shortcut
+	var registeredHotkeys string
+	updateHotkeys := func(targets []SenderTarget) {
+		hotkeys := assignHotkeys(targets)
+		var b strings.Builder
+		for i, h := range hotkeys {
+			fmt.Fprintf(&b, "%p=%s,", senderStates[i], h)
+		}
+		if b.String() == registeredHotkeys {
+			return
+		}
+		registeredHotkeys = b.String()
+
+		senderCanvas := senderWin.Canvas()
+		for _, shortcut := range hotkeyShortcuts {
+			senderCanvas.RemoveShortcut(shortcut)
+		}
+		hotkeyShortcuts = nil
+		hotkeyStates = map[Hotkey]*senderSectionState{}
+		for i, h := range hotkeys {
+			state := senderStates[i]
+			state.SetHotkey(h)
+			if h.Key == "" {
+				continue
+			}
+			hotkeyStates[h] = state
+			// 修飾キーつきはショートカット、単独のキーはOnTypedKeyで受け取る
+			if h.Modifier != 0 {
+				shortcut := &desktop.CustomShortcut{KeyName: h.Key, Modifier: h.Modifier}
+				senderCanvas.AddShortcut(shortcut, func(fyne.Shortcut) {
+					state.Fire()
+				})
+				hotkeyShortcuts = append(hotkeyShortcuts, shortcut)
+			}
+		}
+	}
+
+	// カードの内容をメモリ上の設定に反映する関数
+	syncSenderConfig := func() {
+		targets := make([]SenderTarget, 0, len(senderStates))
+		for _, state := range senderStates {
+			target, _ := state.Target()
+			targets = append(targets, target)
+		}
+		config.Sender.List = targets
+		updateHotkeys(targets)
+		scriptEngine.SetTargets(targets)
+		router.SetTargets(targets)
+	}
+
+	// 他のカードと重ならない名前を作る関数
+	uniqueTargetName := func(base string) string {
+		used := map[string]bool{}
+		for _, state := range senderStates {
+			target, _ := state.Target()
+			used[target.Name] = true
+		}
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s %d", base, n)
+		}
+		return name
+	}
+
+	indexOfSender := func(state *senderSectionState) int {
+		for i, s := range senderStates {
+			if s == state {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// 指定位置にカードを追加する関数
+	var senderActions senderSectionActions
+	insertSender := func(target SenderTarget, at int) *senderSectionState {
+		card, state := createSenderSection(senderWin, target, senderActions, lastValues, updateSendHistory, openLoadTest)
+		senderCards = append(senderCards[:at], append([]*widget.Card{card}, senderCards[at:]...)...)
+		senderStates = append(senderStates[:at], append([]*senderSectionState{state}, senderStates[at:]...)...)
+		return state
+	}
+
+	senderActions = senderSectionActions{
+		Duplicate: func(state *senderSectionState) {
+			i := indexOfSender(state)
+			if i < 0 {
+				return
+			}
+			target, _ := state.Target()
+			target.Name = uniqueTargetName(target.Name + " Copy")
+			insertSender(target, i+1).MarkUnsaved()
+			setListDirty(true)
+			updateSendersDisplay()
+			syncSenderConfig()
+			log.Printf("送信先を複製: %s", target.Name)
+		},
+		Delete: func(state *senderSectionState) {
+			target, _ := state.Target()
+			dialog.ShowConfirm("Delete Target", fmt.Sprintf("Delete \"%s\"?", target.Name), func(ok bool) {
+				i := indexOfSender(state)
+				if !ok || i < 0 {
+					return
+				}
+				state.onRemove()
+				senderCards = append(senderCards[:i], senderCards[i+1:]...)
+				senderStates = append(senderStates[:i], senderStates[i+1:]...)
+				setListDirty(true)
+				updateSendersDisplay()
+				syncSenderConfig()
+				log.Printf("送信先を削除: %s", target.Name)
+			}, senderWin)
+		},
+		Move: func(state *senderSectionState, delta int) {
+			i := indexOfSender(state)
+			j := i + delta
+			if i < 0 || j < 0 || j >= len(senderStates) {
+				return
+			}
+			senderCards[i], senderCards[j] = senderCards[j], senderCards[i]
+			senderStates[i], senderStates[j] = senderStates[j], senderStates[i]
+			setListDirty(true)
+			updateSendersDisplay()
+			syncSenderConfig()
+		},
+		Changed: func() {
+			syncSenderConfig()
+		},
+		NameInUse: func(state *senderSectionState, name string) bool {
+			for _, other := range senderStates {
+				if target, _ := other.Target(); other != state && target.Name == name {
+					return true
+				}
+			}
+			return false
+		},
+		Renamed: func(oldName, newName string) {
+			renameTargetReferences(config, oldName, newName)
+			router.RenameTarget(oldName, newName)
+			log.Printf("転送ルール・マクロの送信先の参照を変更: %s → %s", oldName, newName)
+		},
+		ScriptsReferencing: scriptEngine.ScriptsReferencing,
+		HotkeyInUse: func(state *senderSectionState, h Hotkey) (string, bool) {
+			// 既定の数字キーも含め、いま実際に割り当てられているキーと比べる
+			other, ok := hotkeyStates[h]
+			if !ok || other == state {
+				return "", false
+			}
+			target, _ := other.Target()
+			return target.Name, true
+		},
+	}
+
+	// すべてのカードを送信先の一覧から作り直す関数
+	replaceSenders := func(targets []SenderTarget) {
+		for _, state := range senderStates {
+			state.onRemove()
+		}
+		senderCards, senderStates = nil, nil
+		for _, target := range targets {
+			insertSender(target, len(senderStates))
+		}
+		setListDirty(false)
+		updateSendersDisplay()
+		syncSenderConfig()
+	}
+
 	// 各送信先に対してUIセクションを作成
-	for i, target := range config.Sender.List {
-		sectionCard := createSenderSection(target, i, updateSendHistory)
-		sendersContainer.Add(sectionCard)
+	replaceSenders(config.Sender.List)
+
+	// 新しい送信先を追加するボタン
+	newTargetBtn := widget.NewButton("New Target", func() {
+		state := insertSender(SenderTarget{
+			Name:    uniqueTargetName("New Target"),
+			Host:    "127.0.0.1",
+			Port:    opts.receiverPort(config),
+			Address: "/test",
+		}, len(senderStates))
+		state.MarkUnsaved()
+		setListDirty(true)
+		updateSendersDisplay()
+		syncSenderConfig()
+	})
+
+	// セッション再生ウィンドウ
+	var replayWin fyne.Window
+	replayBtn := widget.NewButton("Replay...", func() {
+		if replayWin == nil {
+			// 再生先の初期値は最初の送信先
+			replayTarget := SenderTarget{Host: "127.0.0.1", Port: opts.receiverPort(config)}
+			if len(config.Sender.List) > 0 {
+				replayTarget = config.Sender.List[0]
+			}
+			replayWin = createReplayWindow(a, replayTarget, updateSendHistory)
+		}
+		replayWin.Show()
+	})
 
-		// 最後以外はセパレータを追加
-		if i < len(config.Sender.List)-1 {
-			sendersContainer.Add(widget.NewSeparator())
+	// マクロ実行ウィンドウ
+	var macroWin fyne.Window
+	var refreshMacros func()
+	macroBtn := widget.NewButton("Macros...", func() {
+		if macroWin == nil {
+			macroWin, refreshMacros = createMacroWindow(a, config, lastValues, updateSendHistory)
+		} else {
+			refreshMacros()
+		}
+		macroWin.Show()
+	})
+
+	// ホットキーの一覧（? で表示・非表示、Escで閉じる）
+	var hotkeySheet *widget.PopUp
+	toggleHotkeySheet := func() {
+		if hotkeySheet != nil && hotkeySheet.Visible() {
+			hotkeySheet.Hide()
+			return
 		}
+		rows := container.New(layout.NewFormLayout())
+		for i, h := range assignHotkeys(config.Sender.List) {
+			if h.Key == "" {
+				continue
+			}
+			rows.Add(widget.NewLabelWithStyle(h.String(), fyne.TextAlignTrailing, fyne.TextStyle{Bold: true, Monospace: true}))
+			rows.Add(widget.NewLabel(config.Sender.List[i].Name))
+		}
+		if len(rows.Objects) == 0 {
+			rows.Add(widget.NewLabel(""))
+			rows.Add(widget.NewLabel("No hotkeys assigned"))
+		}
+		closeBtn := widget.NewButton("Close", func() {
+			hotkeySheet.Hide()
+		})
+		hotkeySheet = widget.NewModalPopUp(container.NewVBox(
+			widget.NewCard("Keyboard Shortcuts", "Press a key to send the target", nil),
+			rows,
+			widget.NewSeparator(),
+			widget.NewLabel("Keys work while no text field has focus (click an empty area first).\n? shows or hides this list, Esc closes it."),
+			container.NewHBox(layout.NewSpacer(), closeBtn),
+		), senderWin.Canvas())
+		hotkeySheet.Show()
+	}
+	hotkeysBtn := widget.NewButton("Keys (?)", toggleHotkeySheet)
+
+	senderWin.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		if ev.Name == fyne.KeyEscape && hotkeySheet != nil && hotkeySheet.Visible() {
+			hotkeySheet.Hide()
+			return
+		}
+		if state, ok := hotkeyStates[Hotkey{Key: ev.Name}]; ok {
+			state.Fire()
+		}
+	})
+	senderWin.Canvas().SetOnTypedRune(func(r rune) {
+		if r == '?' {
+			toggleHotkeySheet()
+		}
+	})
+
+	// スクリプト実行ウィンドウ
+	var scriptWin fyne.Window
+	scriptBtn := widget.NewButton("Scripts...", func() {
+		if scriptWin == nil {
+			scriptWin = createScriptWindow(a, scriptEngine)
+		}
+		scriptWin.Show()
+	})
+
+	// 往復遅延測定ウィンドウ
+	var latencyWin fyne.Window
+	latencyBtn := widget.NewButton("Latency...", func() {
+		if latencyWin == nil {
+			latencyTarget := SenderTarget{Host: "127.0.0.1", Port: opts.receiverPort(config)}
+			if len(config.Sender.List) > 0 {
+				latencyTarget = config.Sender.List[0]
+			}
+			latencyWin = createLatencyWindow(a, latencyTarget)
+		}
+		latencyWin.Show()
+	})
+
+	// 設定ファイルのエラー表示（再読み込みに失敗しても画面はそのまま使える）
+	configErrorLabel := widget.NewLabel("")
+	configErrorLabel.Importance = widget.DangerImportance
+	configErrorLabel.Wrapping = fyne.TextWrapWord
+	configErrorLabel.Hide()
+	if configCheckErr != nil {
+		configErrorLabel.SetText(configCheckErr.Error())
+		configErrorLabel.Show()
 	}
 
+	// 送信先の編集内容を設定ファイルに保存する
+	configFile := settings.ConfigFile
+	// 最後に読み込み・保存した内容（自分の保存による変更通知を無視するため）
+	lastConfigData, configFiles := configSnapshot(configFile)
+	var configWatcher *ConfigWatcher
+	// 使用する設定ファイルを変え、そのファイルとincludeしたファイルを監視する関数
+	watchConfigFile := func(filename string) {
+		configFile = filename
+		lastConfigData, configFiles = configSnapshot(filename)
+		if configWatcher != nil {
+			if err := configWatcher.SetFiles(configFiles); err != nil {
+				log.Printf("設定ファイルの監視エラー: %v", err)
+			}
+		}
+	}
+
+	// 設定ファイル（環境ごとのプロファイル）の切り替え
+	profiles := availableProfiles(settings)
+	profileNames := make([]string, len(profiles))
+	for i, profile := range profiles {
+		profileNames[i] = profile.Name
+	}
+	var switchProfile func(profile ConfigProfile)
+	profileSelect := widget.NewSelect(profileNames, func(name string) {
+		for _, profile := range profiles {
+			if profile.Name == name && !sameFile(profile.ConfigFile, configFile) && switchProfile != nil {
+				switchProfile(profile)
+				return
+			}
+		}
+	})
+	// 使用中の設定ファイルに合わせて選択を戻す関数
+	selectCurrentProfile := func() {
+		for _, profile := range profiles {
+			if sameFile(profile.ConfigFile, configFile) {
+				profileSelect.SetSelected(profile.Name)
+				return
+			}
+		}
+		profileSelect.ClearSelected()
+	}
+	selectCurrentProfile()
+
+	saveSenderConfig := func(filename string) error {
+		targets := make([]SenderTarget, 0, len(senderStates))
+		for _, state := range senderStates {
+			target, err := state.Target()
+			if err != nil {
+				return fmt.Errorf("%s: %w", target.Name, err)
+			}
+			targets = append(targets, target)
+		}
+		config.Sender.List = targets
+		if err := SaveConfig(filename, config); err != nil {
+			return err
+		}
+		for _, state := range senderStates {
+			state.MarkSaved()
+		}
+		setListDirty(false)
+		lastConfigData, _ = configSnapshot(filename)
+		log.Printf("設定を保存しました: %s", filename)
+		return nil
+	}
+
+	saveBtn := widget.NewButton("Save", func() {
+		if err := saveSenderConfig(configFile); err != nil {
+			log.Printf("設定の保存に失敗しました: %v", err)
+			dialog.ShowError(err, senderWin)
+		}
+	})
+	saveAsBtn := widget.NewButton("Save As...", func() {
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			// ファイルはSaveConfigで書き直す
+			path := writer.URI().Path()
+			writer.Close()
+
+			if err := saveSenderConfig(path); err != nil {
+				log.Printf("設定の保存に失敗しました: %v", err)
+				dialog.ShowError(err, senderWin)
+				return
+			}
+			// 以降のSaveは保存先のファイルに書き、そのファイルを監視する
+			watchConfigFile(path)
+			selectCurrentProfile()
+		}, senderWin)
+		saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".yaml", ".yml"}))
+		saveDialog.SetFileName(filepath.Base(configFile))
+		saveDialog.Show()
+	})
+
+	// 未保存の変更があれば閉じる前に確認する
+	senderWin.SetCloseIntercept(func() {
+		dirty := listDirty
+		for _, state := range senderStates {
+			dirty = dirty || state.IsDirty()
+		}
+		if !dirty {
+			senderWin.Close()
+			return
+		}
+		dialog.ShowConfirm("Unsaved Changes", "Sender targets have unsaved changes. Close without saving?", func(ok bool) {
+			if ok {
+				senderWin.Close()
+			}
+		}, senderWin)
+	})
+
 	// メインレイアウト
 	senderContent := container.NewBorder(
-		widget.NewCard("OSC Sender", "", nil), // top
+		container.NewVBox(
+			container.NewBorder(nil, nil, nil, container.NewCenter(container.NewHBox(widget.NewLabel("Profile:"), profileSelect, widget.NewSeparator(), listDirtyLabel, newTargetBtn, saveBtn, saveAsBtn, widget.NewSeparator(), macroBtn, scriptBtn, replayBtn, latencyBtn, hotkeysBtn)), widget.NewCard("OSC Sender", "", nil)),
+			configErrorLabel,
+		), // top
 		container.NewVBox(
 			widget.NewSeparator(),
 			widget.NewLabel("Send History:"),
@@ -536,14 +1698,16 @@ func main() {
 
 	senderWin.SetContent(senderContent)
 	senderWin.Resize(fyne.NewSize(float32(config.Sender.Window.Width), float32(config.Sender.Window.Height)))
-	senderWin.Show()
+	if !opts.NoSender {
+		senderWin.Show()
+	}
 
 	// Receiverウィンドウ
 	receiverWin := a.NewWindow(config.Receiver.Window.Title)
 
 	// OSC受信用のUI要素
 	receiverPortEntry := widget.NewEntry()
-	receiverPortEntry.SetText(fmt.Sprintf("%d", config.Receiver.DefaultPort))
+	receiverPortEntry.SetText(strconv.Itoa(opts.receiverPort(config)))
 	receiverPortEntry.SetPlaceHolder("Port Number")
 	receiverPortEntry.Resize(fyne.NewSize(80, 32))
 
@@ -561,26 +1725,23 @@ func main() {
 	// 受信メッセージカウンタ
 	messageCountLabel := widget.NewLabel("Received: 0")
 
+	// フィルターに一致するメッセージを返す関数（新しい順）
+	filteredMessages := func() []OSCMessage {
+		var filtered []OSCMessage
+		for _, msg := range messages {
+			if matchAddressFilter(filterEntry.Text, msg.Address) {
+				filtered = append(filtered, msg)
+			}
+		}
+		return filtered
+	}
+
 	// ログコンテンツを更新する関数
 	updateLogContent := func() {
 		var logText string
-		filter := filterEntry.Text
 
-		for _, msg := range messages {
-			// フィルター機能
-			shouldShow := false
-			if filter == "" {
-				shouldShow = true
-			} else if strings.HasSuffix(filter, "*") {
-				prefix := strings.TrimSuffix(filter, "*")
-				shouldShow = strings.HasPrefix(msg.Address, prefix)
-			} else {
-				shouldShow = strings.Contains(msg.Address, filter)
-			}
-
-			if shouldShow {
-				logText += fmt.Sprintf("%s | %s | %s\n", msg.Timestamp, msg.Address, msg.Values)
-			}
+		for _, msg := range filteredMessages() {
+			logText += fmt.Sprintf("%s | %s | %s\n", msg.Timestamp, msg.Address, msg.Values)
 		}
 		if logText == "" {
 			logText = "Message log will be displayed here"
@@ -594,14 +1755,7 @@ func main() {
 	}
 
 	// メッセージ追加関数
-	addMessage := func(address, values string) {
-		timestamp := time.Now().Format("15:04:05")
-		newMsg := OSCMessage{
-			Timestamp: timestamp,
-			Address:   address,
-			Values:    values,
-		}
-
+	addMessage := func(newMsg OSCMessage) {
 		messages = append([]OSCMessage{newMsg}, messages...)
 		if len(messages) > config.Receiver.MaxLogEntries {
 			messages = messages[:config.Receiver.MaxLogEntries]
@@ -611,11 +1765,50 @@ func main() {
 		updateLogContent()
 	}
 
+	// 受信メッセージの転送
+	var routerWin fyne.Window
+	routerBtn := widget.NewButton("Routes...", func() {
+		if routerWin == nil {
+			routerWin = createRouterWindow(a, router, func() []SenderTarget {
+				return config.Sender.List
+			}, func() {
+				// 編集内容をメモリ上の設定に反映
+				config.Router = router.Settings()
+			})
+		}
+		routerWin.Show()
+	})
+
+	// シーケンス番号の監視
+	sequenceTracker := NewSequenceTracker()
+	var sequenceWin fyne.Window
+	sequenceBtn := widget.NewButton("Sequence...", func() {
+		if sequenceWin == nil {
+			sequenceWin = createSequenceWindow(a, sequenceTracker)
+		}
+		sequenceWin.Show()
+	})
+
+	// WebSocketブリッジのウィンドウ
+	var websocketWin fyne.Window
+	websocketBtn := widget.NewButton("WebSocket...", func() {
+		if websocketWin == nil {
+			websocketWin = createWebSocketWindow(a, bridge, func() WebSocketSettings {
+				return config.Receiver.WebSocket
+			})
+		}
+		websocketWin.Show()
+	})
+
 	// 受信制御用の変数
 	var startStopBtn *widget.Button
-	var oscServer *osc.Server
+	var oscReceiver *OSCReceiver
 	var isReceiving bool
 
+	// セッション記録用の変数
+	var recordBtn *widget.Button
+	var sessionWriter *SessionWriter
+
 	// Start/Stopボタン
 	startStopBtn = widget.NewButton("Start", func() {
 		if !isReceiving {
@@ -633,39 +1826,33 @@ func main() {
 
 			addr := fmt.Sprintf("127.0.0.1:%d", port)
 
-			// ディスパッチャーを作成
-			dispatcher := osc.NewStandardDispatcher()
-
-			// すべてのメッセージを受信するハンドラを追加
-			dispatcher.AddMsgHandler("*", func(msg *osc.Message) {
-				address := msg.Address
-
-				// 引数を文字列に変換
-				var values []string
-				for _, arg := range msg.Arguments {
-					values = append(values, fmt.Sprintf("%v", arg))
-				}
-				valuesStr := strings.Join(values, ", ")
+			// 受信を開始（すべてのメッセージを受け取る）
+			oscReceiver, err = StartOSCReceiver(addr, func(msg OSCMessage) {
+				router.Handle(msg)
+				sequenceTracker.Handle(msg)
+				lastValues.Handle(msg)
+				scriptEngine.Handle(msg)
+				bridge.Handle(msg)
 
 				// UIスレッドで更新
-				addMessage(address, valuesStr)
-				log.Printf("OSC受信: %s [%s]", address, valuesStr)
+				fyne.Do(func() {
+					addMessage(msg)
+					if sessionWriter != nil {
+						if err := sessionWriter.Write(sessionRecordFromMessage(msg)); err != nil {
+							log.Printf("セッション記録エラー: %v", err)
+						}
+					}
+				})
+				log.Printf("OSC受信: %s [%s]", msg.Address, msg.Values)
 			})
-
-			// サーバーを作成
-			oscServer = &osc.Server{
-				Addr:       addr,
-				Dispatcher: dispatcher,
+			if err != nil {
+				log.Printf("OSC受信エラー: %v", err)
+				statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				statusLabel.Importance = widget.DangerImportance
+				statusLabel.Refresh()
+				return
 			}
 
-			// サーバー開始
-			go func() {
-				err := oscServer.ListenAndServe()
-				if err != nil {
-					log.Printf("OSC受信エラー: %v", err)
-				}
-			}()
-
 			startStopBtn.SetText("Stop")
 			statusLabel.SetText("Receiving...")
 			statusLabel.Importance = widget.SuccessImportance
@@ -673,8 +1860,9 @@ func main() {
 			log.Printf("OSC受信を開始 (ポート: %d)", port)
 		} else {
 			// OSC受信停止
-			if oscServer != nil {
-				oscServer = nil
+			if oscReceiver != nil {
+				oscReceiver.Close()
+				oscReceiver = nil
 			}
 			startStopBtn.SetText("Start")
 			statusLabel.SetText("Stopped")
@@ -694,6 +1882,119 @@ func main() {
 		updateLogContent()
 	})
 
+	// 記録ボタン（受信したメッセージをセッションファイルに保存）
+	recordBtn = widget.NewButton("Record", func() {
+		if sessionWriter != nil {
+			count := sessionWriter.Count()
+			if err := sessionWriter.Close(); err != nil {
+				log.Printf("セッションファイルのクローズエラー: %v", err)
+			}
+			sessionWriter = nil
+			recordBtn.SetText("Record")
+			recordBtn.Importance = widget.MediumImportance
+			recordBtn.Refresh()
+			log.Printf("セッション記録を終了 (%d件)", count)
+			return
+		}
+
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			// ファイルはSessionWriterで開き直す
+			path := writer.URI().Path()
+			writer.Close()
+
+			w, err := CreateSessionWriter(path)
+			if err != nil {
+				dialog.ShowError(err, receiverWin)
+				return
+			}
+			sessionWriter = w
+			recordBtn.SetText("Stop Recording")
+			recordBtn.Importance = widget.DangerImportance
+			recordBtn.Refresh()
+			log.Printf("セッション記録を開始: %s", path)
+		}, receiverWin)
+		saveDialog.SetFileName(fmt.Sprintf("session-%s.jsonl", time.Now().Format("20060102-150405")))
+		saveDialog.Show()
+	})
+
+	// エクスポートボタン（表示中のメッセージを拡張子に応じてCSV/JSON/テキスト/pcapngで保存）
+	exportBtn := widget.NewButton("Export", func() {
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+
+			// 古い順に書き出す
+			filtered := filteredMessages()
+			for i, j := 0, len(filtered)-1; i < j; i, j = i+1, j-1 {
+				filtered[i], filtered[j] = filtered[j], filtered[i]
+			}
+
+			format := exportFormatFromFilename(writer.URI().Path())
+			if err := ExportMessages(writer, format, filtered); err != nil {
+				log.Printf("エクスポートエラー: %v", err)
+				dialog.ShowError(err, receiverWin)
+				return
+			}
+			log.Printf("受信ログをエクスポート: %s (%s, %d件)", writer.URI().Path(), format, len(filtered))
+		}, receiverWin)
+		saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".json", ".txt", ".pcapng"}))
+		saveDialog.SetFileName(fmt.Sprintf("osc-log-%s.csv", time.Now().Format("20060102-150405")))
+		saveDialog.Show()
+	})
+
+	// インポートボタン（pcap/pcapngからOSCメッセージを読み込む）
+	importBtn := widget.NewButton("Import", func() {
+		portsEntry := widget.NewEntry()
+		portsEntry.SetText(receiverPortEntry.Text)
+		portsEntry.SetPlaceHolder("e.g. 7000, 8000 (empty=all UDP)")
+
+		dialog.ShowForm("Import Capture", "Open...", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("UDP Ports", portsEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			ports, err := parsePortList(portsEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, receiverWin)
+				return
+			}
+
+			openDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+				if err != nil || reader == nil {
+					return
+				}
+				path := reader.URI().Path()
+				reader.Close()
+
+				result, err := ImportCapture(path, ports)
+				if err != nil {
+					log.Printf("キャプチャ読み込みエラー: %v", err)
+					dialog.ShowError(err, receiverWin)
+					return
+				}
+
+				// 読み込んだメッセージで置き換える（表示は新しい順）
+				messages = make([]OSCMessage, 0, len(result.Messages))
+				for i := len(result.Messages) - 1; i >= 0; i-- {
+					messages = append(messages, result.Messages[i])
+				}
+				messageCountLabel.SetText(fmt.Sprintf("Received: %d", len(messages)))
+				updateLogContent()
+
+				log.Printf("キャプチャを読み込みました: %s (フレーム %d, UDP %d, OSC %d件, 解析エラー %d)",
+					path, result.Frames, result.Datagram, len(result.Messages), result.Invalid)
+			}, receiverWin)
+			openDialog.SetFilter(storage.NewExtensionFileFilter([]string{".pcap", ".pcapng", ".cap"}))
+			openDialog.Show()
+		}, receiverWin)
+	})
+
 	// Receiverレイアウト構成
 	receiverTopSection := container.NewVBox(
 		widget.NewCard("OSC Receiver", "", nil),
@@ -717,6 +2018,10 @@ func main() {
 			widget.NewSeparator(),
 			statusLabel,
 			layout.NewSpacer(),
+			routerBtn,
+			sequenceBtn,
+			websocketBtn,
+			recordBtn,
 		),
 
 		widget.NewSeparator(),
@@ -737,6 +2042,8 @@ func main() {
 		container.NewHBox(
 			widget.NewLabelWithStyle("Message Log", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 			clearBtn,
+			exportBtn,
+			importBtn,
 			layout.NewSpacer(),
 		),
 	)
@@ -752,7 +2059,177 @@ func main() {
 
 	receiverWin.SetContent(receiverContent)
 	receiverWin.Resize(fyne.NewSize(float32(config.Receiver.Window.Width), float32(config.Receiver.Window.Height)))
-	receiverWin.Show()
+	if !opts.NoReceiver {
+		receiverWin.Show()
+	}
+
+	// 片方のウィンドウだけを開いた場合は、そのウィンドウを閉じたら終了する
+	switch {
+	case opts.NoSender:
+		receiverWin.SetMaster()
+	case opts.NoReceiver:
+		senderWin.SetMaster()
+	}
+
+	// 未保存の編集があるかどうか
+	hasUnsavedEdits := func() bool {
+		dirty := listDirty
+		for _, state := range senderStates {
+			dirty = dirty || state.IsDirty()
+		}
+		return dirty
+	}
+
+	// 設定ファイルの内容を検証して読み込む関数
+	parseConfigData := func(filename string, data []byte) (*AppConfig, error) {
+		err := ValidateConfigData(filename, data)
+		var newConfig *AppConfig
+		if err == nil {
+			newConfig, err = LoadConfig(filename)
+		}
+		if err != nil {
+			log.Printf("設定ファイル %s を適用できません:\n%v", filename, err)
+			return nil, err
+		}
+		return newConfig, nil
+	}
+
+	// 読み込んだ設定を適用し、送信先カードと受信設定をその場で更新する関数
+	applyConfig := func(filename string, newConfig *AppConfig) {
+		*config = *newConfig
+		// includeするファイルが変わっていることもあるので監視し直す
+		watchConfigFile(filename)
+
+		replaceSenders(config.Sender.List)
+		if err := router.Load(config.Router, config.Sender.List); err != nil {
+			log.Printf("転送ルールを読み込めません: %v", err)
+		}
+
+		senderWin.SetTitle(config.Sender.Window.Title)
+		receiverWin.SetTitle(config.Receiver.Window.Title)
+		if !isReceiving {
+			receiverPortEntry.SetText(strconv.Itoa(opts.receiverPort(config)))
+		}
+		if len(messages) > config.Receiver.MaxLogEntries {
+			messages = messages[:config.Receiver.MaxLogEntries]
+			messageCountLabel.SetText(fmt.Sprintf("Received: %d", len(messages)))
+			updateLogContent()
+		}
+
+		if refreshMacros != nil {
+			refreshMacros()
+		}
+		// スクリプトは最初から実行し直す
+		if err := scriptEngine.Load(config.Scripts, filename, config.Sender.List); err != nil {
+			log.Printf("%v", err)
+		}
+		// 許可するOriginと送信先は動いているWebSocketブリッジにもすぐ反映する
+		bridge.SetSettings(config.Receiver.WebSocket)
+		// WebSocketブリッジは動いていなければautostartに従って開始する（動いていればそのまま）
+		if running, _ := bridge.Running(); !running && config.Receiver.WebSocket.Autostart {
+			if err := bridge.Start(config.Receiver.WebSocket.Addr()); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+
+		configErrorLabel.Hide()
+		log.Printf("設定ファイルを適用しました: %s", filename)
+	}
+
+	// 設定ファイルを読み込み直す関数（ファイルの変更を検出したとき）
+	reloadConfig := func() {
+		snapshot, _ := configSnapshot(configFile)
+		if bytes.Equal(snapshot, lastConfigData) {
+			return
+		}
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return
+		}
+
+		// 問題のある設定は適用しない
+		// 使用中のファイルの問題なので、直すまで画面に表示しておく
+		filename := configFile
+		newConfig, err := parseConfigData(filename, data)
+		if err != nil {
+			configErrorLabel.SetText(err.Error())
+			configErrorLabel.Show()
+			return
+		}
+
+		// 未保存の編集があれば破棄してよいか確認する
+		if !hasUnsavedEdits() {
+			applyConfig(filename, newConfig)
+			return
+		}
+		dialog.ShowConfirm("Config Changed",
+			fmt.Sprintf("%s was changed on disk. Reload it and discard unsaved edits?", filepath.Base(filename)),
+			func(ok bool) {
+				if ok {
+					applyConfig(filename, newConfig)
+				} else {
+					// 同じ内容では再度確認しない
+					lastConfigData = snapshot
+				}
+			}, senderWin)
+	}
+
+	// 選択したプロファイルの設定ファイルに切り替える
+	switchProfile = func(profile ConfigProfile) {
+		data, err := os.ReadFile(profile.ConfigFile)
+		if err != nil {
+			log.Printf("設定ファイル %s を読み込めません: %v", profile.ConfigFile, err)
+			dialog.ShowError(err, senderWin)
+			selectCurrentProfile()
+			return
+		}
+		// 切り替え先の問題は使用中のプロファイルのものではないので、ダイアログで知らせる
+		newConfig, err := parseConfigData(profile.ConfigFile, data)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("profile %q was not loaded:\n%v", profile.Name, err), senderWin)
+			selectCurrentProfile()
+			return
+		}
+
+		if !hasUnsavedEdits() {
+			applyConfig(profile.ConfigFile, newConfig)
+			return
+		}
+		dialog.ShowConfirm("Switch Profile",
+			fmt.Sprintf("Switch to %s and discard unsaved edits?", profile.Name),
+			func(ok bool) {
+				if ok {
+					applyConfig(profile.ConfigFile, newConfig)
+				} else {
+					selectCurrentProfile()
+				}
+			}, senderWin)
+	}
+
+	// autostartのスクリプトを実行する
+	if err := scriptEngine.Load(config.Scripts, configFile, config.Sender.List); err != nil {
+		log.Printf("%v", err)
+	}
+	defer scriptEngine.StopAll()
+
+	// autostartならWebSocketブリッジを開始する
+	bridge.SetSettings(config.Receiver.WebSocket)
+	if config.Receiver.WebSocket.Autostart {
+		if err := bridge.Start(config.Receiver.WebSocket.Addr()); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+	defer bridge.Stop()
+
+	// 設定ファイルの変更を監視する
+	configWatcher, err = NewConfigWatcher(configFiles, func(string) {
+		fyne.Do(reloadConfig)
+	})
+	if err != nil {
+		log.Printf("設定ファイルを監視できません: %v", err)
+	} else {
+		defer configWatcher.Close()
+	}
 
 	a.Run()
 }
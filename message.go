@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// senderArgumentTypes 送信UIで選択できる引数タイプ
+var senderArgumentTypes = []string{"int", "float", "string", "bool", "int64", "double", "timetag", "blob", "nil"}
+
+// OSCValue 引数をOSCメッセージに追加できる値に変換する
+func (arg OSCArgument) OSCValue() (interface{}, error) {
+	switch arg.Type {
+	case "int":
+		val, err := strconv.ParseInt(arg.Value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid int: %q", arg.Value)
+		}
+		return int32(val), nil
+	case "float":
+		val, err := strconv.ParseFloat(arg.Value, 32)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid float: %q", arg.Value)
+		}
+		return float32(val), nil
+	case "string":
+		return arg.Value, nil
+	case "bool":
+		val, err := strconv.ParseBool(arg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid bool: %q", arg.Value)
+		}
+		return val, nil
+	case "int64":
+		val, err := strconv.ParseInt(arg.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid int64: %q", arg.Value)
+		}
+		return val, nil
+	case "double":
+		val, err := strconv.ParseFloat(arg.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid double: %q", arg.Value)
+		}
+		return val, nil
+	case "blob":
+		val, err := base64.StdEncoding.DecodeString(arg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid blob: %q", arg.Value)
+		}
+		return val, nil
+	case "timetag":
+		val, err := strconv.ParseUint(arg.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid timetag: %q", arg.Value)
+		}
+		return *osc.NewTimetagFromTimetag(val), nil
+	case "nil":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported argument type: %s", arg.Type)
+}
+
+// TypeTag 引数タイプに対応するOSCタイプタグを返す
+func (arg OSCArgument) TypeTag() string {
+	switch arg.Type {
+	case "int":
+		return "i"
+	case "float":
+		return "f"
+	case "string":
+		return "s"
+	case "bool":
+		if val, err := strconv.ParseBool(arg.Value); err == nil && !val {
+			return "F"
+		}
+		return "T"
+	case "int64":
+		return "h"
+	case "double":
+		return "d"
+	case "blob":
+		return "b"
+	case "timetag":
+		return "t"
+	case "nil":
+		return "N"
+	}
+	return "?"
+}
+
+// oscArgumentFromValue 受信した引数の値をOSCArgumentに変換する
+func oscArgumentFromValue(value interface{}) OSCArgument {
+	switch v := value.(type) {
+	case int32:
+		return OSCArgument{Type: "int", Value: strconv.FormatInt(int64(v), 10)}
+	case float32:
+		return OSCArgument{Type: "float", Value: strconv.FormatFloat(float64(v), 'g', -1, 32)}
+	case string:
+		return OSCArgument{Type: "string", Value: v}
+	case bool:
+		return OSCArgument{Type: "bool", Value: strconv.FormatBool(v)}
+	case int64:
+		return OSCArgument{Type: "int64", Value: strconv.FormatInt(v, 10)}
+	case float64:
+		return OSCArgument{Type: "double", Value: strconv.FormatFloat(v, 'g', -1, 64)}
+	case []byte:
+		return OSCArgument{Type: "blob", Value: base64.StdEncoding.EncodeToString(v)}
+	case osc.Timetag:
+		return OSCArgument{Type: "timetag", Value: strconv.FormatUint(v.TimeTag(), 10)}
+	case nil:
+		return OSCArgument{Type: "nil"}
+	}
+	return OSCArgument{Type: "string", Value: fmt.Sprintf("%v", value)}
+}
+
+// oscArgumentsFromValues 受信した引数リストをOSCArgumentのリストに変換する
+func oscArgumentsFromValues(values []interface{}) []OSCArgument {
+	arguments := make([]OSCArgument, 0, len(values))
+	for _, value := range values {
+		arguments = append(arguments, oscArgumentFromValue(value))
+	}
+	return arguments
+}
+
+// buildOSCMessage アドレスと引数からOSCメッセージを作成する
+func buildOSCMessage(address string, arguments []OSCArgument) (*osc.Message, error) {
+	msg := osc.NewMessage(address)
+	for _, arg := range arguments {
+		value, err := arg.OSCValue()
+		if err != nil {
+			return nil, err
+		}
+		msg.Append(value)
+	}
+	return msg, nil
+}
+
+// formatArguments 引数を "type:value" 形式の文字列にまとめる
+func formatArguments(arguments []OSCArgument) string {
+	var argInfo []string
+	for _, arg := range arguments {
+		argInfo = append(argInfo, fmt.Sprintf("%s:%s", arg.Type, arg.Value))
+	}
+	return strings.Join(argInfo, ", ")
+}
+
+// matchAddressFilter アドレスフィルターに一致するか判定する
+// 空=すべて、末尾*=前方一致、それ以外=部分一致
+func matchAddressFilter(filter, address string) bool {
+	if filter == "" {
+		return true
+	}
+	if strings.HasSuffix(filter, "*") {
+		return strings.HasPrefix(address, strings.TrimSuffix(filter, "*"))
+	}
+	return strings.Contains(address, filter)
+}
+
+// flattenPacket バンドルを展開してメッセージのリストにする
+func flattenPacket(packet osc.Packet) []*osc.Message {
+	switch p := packet.(type) {
+	case *osc.Message:
+		return []*osc.Message{p}
+	case *osc.Bundle:
+		messages := append([]*osc.Message{}, p.Messages...)
+		for _, b := range p.Bundles {
+			messages = append(messages, flattenPacket(b)...)
+		}
+		return messages
+	}
+	return nil
+}
+
+// durationFromSeconds 秒数を表す文字列をDurationに変換する（空文字は0）
+func durationFromSeconds(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	sec, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number of seconds: %s", text)
+	}
+	return time.Duration(sec * float64(time.Second)), nil
+}
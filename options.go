@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 起動オプションを指定する環境変数（コマンドラインのフラグが優先）
+const (
+	envSettingsFile = "GO_OSC_CHECKER_SETTINGS"
+	envConfigFile   = "GO_OSC_CHECKER_CONFIG"
+	envReceiverPort = "GO_OSC_CHECKER_RECEIVER_PORT"
+	envNoSender     = "GO_OSC_CHECKER_NO_SENDER"
+	envNoReceiver   = "GO_OSC_CHECKER_NO_RECEIVER"
+)
+
+// defaultSettingsFile settings.yamlの既定の場所（各検索ディレクトリからの相対パス）
+const defaultSettingsFile = "settings/settings.yaml"
+
+// appConfigDirName XDG設定ディレクトリ内のこのアプリのディレクトリ名
+const appConfigDirName = "go-osc-checker"
+
+// LaunchOptions GUI起動時のオプション
+type LaunchOptions struct {
+	SettingsFile string // 空なら検索ディレクトリから settings/settings.yaml を探す
+	ConfigFile   string // 空ならsettings.yamlの指定に従う
+	ReceiverPort int    // 0なら設定ファイルのdefault_port
+	NoSender     bool
+	NoReceiver   bool
+}
+
+// parseLaunchOptions コマンドラインのフラグと環境変数から起動オプションを読み取る
+func parseLaunchOptions(args []string) (*LaunchOptions, error) {
+	// settings・configの環境変数はresolveSettingsで読む
+	opts := &LaunchOptions{}
+	if value := os.Getenv(envReceiverPort); value != "" {
+		port, err := parsePort(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envReceiverPort, err)
+		}
+		opts.ReceiverPort = port
+	}
+	for name, value := range map[string]*bool{envNoSender: &opts.NoSender, envNoReceiver: &opts.NoReceiver} {
+		if text := os.Getenv(name); text != "" {
+			enabled, err := strconv.ParseBool(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid value (use true or false): %s", name, text)
+			}
+			*value = enabled
+		}
+	}
+
+	fs := flag.NewFlagSet("go-osc-checker", flag.ContinueOnError)
+	fs.StringVar(&opts.SettingsFile, "settings", "", "settings file (env "+envSettingsFile+")")
+	fs.StringVar(&opts.ConfigFile, "config", "", "config file, overrides config_file in the settings file (env "+envConfigFile+")")
+	receiverPort := fs.String("receiver-port", "", "receiver port, overrides receiver.default_port (env "+envReceiverPort+")")
+	fs.BoolVar(&opts.NoSender, "no-sender", opts.NoSender, "do not open the sender window (env "+envNoSender+")")
+	fs.BoolVar(&opts.NoReceiver, "no-receiver", opts.NoReceiver, "do not open the receiver window (env "+envNoReceiver+")")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: go-osc-checker [flags]")
+		fmt.Fprintln(fs.Output(), "       go-osc-checker replay|loadgen|validate|macro [flags] ...")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nWithout -settings, %s is looked up in the working directory, the executable's directory and %s.\n",
+			defaultSettingsFile, filepath.Join("$XDG_CONFIG_HOME", appConfigDirName))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return nil, fmt.Errorf("unknown argument: %s", fs.Arg(0))
+	}
+
+	if *receiverPort != "" {
+		port, err := parsePort(*receiverPort)
+		if err != nil {
+			return nil, fmt.Errorf("-receiver-port: %w", err)
+		}
+		opts.ReceiverPort = port
+	}
+	// フラグで片方を指定したら、もう片方の環境変数より優先する
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	switch {
+	case set["no-sender"] && !set["no-receiver"] && opts.NoSender:
+		opts.NoReceiver = false
+	case set["no-receiver"] && !set["no-sender"] && opts.NoReceiver:
+		opts.NoSender = false
+	}
+	if opts.NoSender && opts.NoReceiver {
+		return nil, errors.New("-no-sender and -no-receiver cannot be used together")
+	}
+	return opts, nil
+}
+
+// configSearchDirs 設定ファイルを探すディレクトリ（作業ディレクトリ、実行ファイルのディレクトリ、XDG設定ディレクトリの順）
+func configSearchDirs() []string {
+	dirs := []string{"."}
+	if exe, err := os.Executable(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		dirs = append(dirs, filepath.Dir(exe))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, appConfigDirName))
+	}
+	return dirs
+}
+
+// lookupFile 相対パスのファイルを検索ディレクトリから探す
+// preferredのディレクトリを順に最初に探す。見つからなければnameをそのまま返す
+func lookupFile(name string, preferred ...string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	dirs := append(append([]string(nil), preferred...), configSearchDirs()...)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return name
+}
+
+// resolveSettings settings.yamlを読み込み、使用する設定ファイルのパスを決める
+// settingsFile・configFileが空なら環境変数と検索ディレクトリから探す
+func resolveSettings(settingsFile, configFile string) (*Settings, error) {
+	if settingsFile == "" {
+		settingsFile = os.Getenv(envSettingsFile)
+	}
+	// 明示されたsettings.yamlはそのまま使い、既定のものは検索する
+	if settingsFile == "" {
+		settingsFile = lookupFile(defaultSettingsFile)
+	}
+	// config_file はsettings.yamlのディレクトリか、その親からの相対パス
+	// （settings/settings.yaml では "settings/config.yaml" のように親ディレクトリから書く）
+	settingsDir := filepath.Dir(settingsFile)
+	baseDirs := []string{settingsDir, filepath.Dir(settingsDir)}
+
+	settings, err := LoadSettings(settingsFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", settingsFile, err)
+	}
+
+	if configFile == "" {
+		configFile = os.Getenv(envConfigFile)
+	}
+	if configFile != "" {
+		settings.ConfigFile = configFile
+	} else {
+		settings.ConfigFile = lookupFile(settings.ConfigFile, baseDirs...)
+	}
+	for i := range settings.Profiles {
+		settings.Profiles[i].ConfigFile = lookupFile(settings.Profiles[i].ConfigFile, baseDirs...)
+	}
+	return settings, nil
+}
+
+// availableProfiles 切り替えられる設定ファイルの一覧を返す
+// settings.yamlにprofilesがなければ、使用中の設定ファイルと同じディレクトリの config*.yaml を並べる
+func availableProfiles(settings *Settings) []ConfigProfile {
+	profiles := append([]ConfigProfile(nil), settings.Profiles...)
+	if len(profiles) == 0 {
+		matches, _ := filepath.Glob(filepath.Join(filepath.Dir(settings.ConfigFile), "config*.yaml"))
+		for _, file := range matches {
+			profiles = append(profiles, ConfigProfile{ConfigFile: file})
+		}
+	}
+
+	// 使用中の設定ファイルが一覧になければ先頭に加える
+	found := false
+	for _, profile := range profiles {
+		found = found || sameFile(profile.ConfigFile, settings.ConfigFile)
+	}
+	if !found {
+		profiles = append([]ConfigProfile{{ConfigFile: settings.ConfigFile}}, profiles...)
+	}
+
+	// 選択肢は名前で区別するので、重複した名前には番号を付ける
+	used := map[string]bool{}
+	for i := range profiles {
+		if profiles[i].Name == "" {
+			profiles[i].Name = profileName(profiles[i].ConfigFile)
+		}
+		name := profiles[i].Name
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s (%d)", profiles[i].Name, n)
+		}
+		profiles[i].Name, used[name] = name, true
+	}
+	return profiles
+}
+
+// profileName ファイル名からプロファイル名を作る（config-development.yaml → development）
+func profileName(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if trimmed := strings.TrimPrefix(name, "config-"); trimmed != "" {
+		return trimmed
+	}
+	return name
+}
+
+// sameFile 2つのパスが同じファイルを指しているかどうか
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+// receiverPort 受信ポートの初期値を返す（-receiver-portの指定は設定ファイルには保存しない）
+func (o *LaunchOptions) receiverPort(config *AppConfig) int {
+	if o.ReceiverPort > 0 {
+		return o.ReceiverPort
+	}
+	return config.Receiver.DefaultPort
+}
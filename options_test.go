@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestParseLaunchOptionsWindows(t *testing.T) {
+	tests := []struct {
+		name         string
+		noSender     string // 環境変数の値
+		noReceiver   string
+		args         []string
+		wantSender   bool
+		wantReceiver bool
+		wantErr      bool
+	}{
+		{name: "none"},
+		{name: "env", noSender: "true", wantSender: true},
+		{name: "env false", noSender: "false", noReceiver: "0"},
+		{name: "flag", args: []string{"-no-receiver"}, wantReceiver: true},
+		{name: "flag overrides env", noSender: "1", args: []string{"-no-receiver"}, wantReceiver: true},
+		{name: "flag false", noSender: "1", args: []string{"-no-sender=false"}},
+		{name: "both env", noSender: "1", noReceiver: "1", wantErr: true},
+		{name: "both flags", args: []string{"-no-sender", "-no-receiver"}, wantErr: true},
+		{name: "invalid env", noSender: "yes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envNoSender, tt.noSender)
+			t.Setenv(envNoReceiver, tt.noReceiver)
+			t.Setenv(envReceiverPort, "")
+			opts, err := parseLaunchOptions(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLaunchOptions(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if opts.NoSender != tt.wantSender || opts.NoReceiver != tt.wantReceiver {
+				t.Errorf("NoSender, NoReceiver = %v, %v, want %v, %v", opts.NoSender, opts.NoReceiver, tt.wantSender, tt.wantReceiver)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// oscPatternCache コンパイル済みのアドレスパターン
+var oscPatternCache sync.Map
+
+// compileOSCPattern OSCアドレスパターンを正規表現に変換する
+// * ? [abc] [!a-z] {foo,bar} に対応し、ワイルドカードごとにキャプチャグループを作る
+// OSC 1.1 の // は任意の階層に一致する（キャプチャグループは作らない）
+func compileOSCPattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := oscPatternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			expr.WriteString("([^/]*)")
+		case '?':
+			expr.WriteString("([^/])")
+		case '[':
+			end := indexRune(runes, i, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in pattern: %s", pattern)
+			}
+			expr.WriteString("([")
+			for j, cc := range runes[i+1 : end] {
+				switch {
+				case j == 0 && cc == '!':
+					expr.WriteString("^")
+				case cc == '-':
+					expr.WriteString("-")
+				default:
+					expr.WriteString(regexp.QuoteMeta(string(cc)))
+				}
+			}
+			expr.WriteString("])")
+			i = end
+		case '{':
+			end := indexRune(runes, i, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed { in pattern: %s", pattern)
+			}
+			var choices []string
+			for _, choice := range strings.Split(string(runes[i+1:end]), ",") {
+				choices = append(choices, regexp.QuoteMeta(choice))
+			}
+			expr.WriteString("(" + strings.Join(choices, "|") + ")")
+			i = end
+		case '/':
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				expr.WriteString("/(?:.*/)?")
+				i++
+				continue
+			}
+			expr.WriteString("/")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %s", pattern)
+	}
+	oscPatternCache.Store(pattern, re)
+	return re, nil
+}
+
+// matchOSCPattern アドレスがOSCアドレスパターンに一致するか判定する
+func matchOSCPattern(pattern, address string) bool {
+	re, err := compileOSCPattern(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(address)
+}
+
+// indexRune runes[from:]の中で最初にrが現れる位置を返す（見つからなければ-1）
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
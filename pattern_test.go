@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestCompileOSCPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		address string
+		match   bool
+		groups  []string // ワイルドカードごとのキャプチャ
+	}{
+		{"/1/fader1", "/1/fader1", true, nil},
+		{"/1/fader1", "/1/fader10", false, nil},
+		{"/1/fader*", "/1/fader12", true, []string{"12"}},
+		{"/1/fader*", "/1/fader", true, []string{""}},
+		{"/1/*", "/1/fader/x", false, nil}, // * は / をまたがない
+		{"/*/fader?", "/2/fader3", true, []string{"2", "3"}},
+		{"/fader?", "/fader10", false, nil},
+		{"/fader[1-3]", "/fader2", true, []string{"2"}},
+		{"/fader[1-3]", "/fader4", false, nil},
+		{"/fader[!1-3]", "/fader4", true, []string{"4"}},
+		{"/fader[!1-3]", "/fader1", false, nil},
+		{"/{fader,knob}1", "/knob1", true, []string{"knob"}},
+		{"/{fader,knob}1", "/button1", false, nil},
+		{"/a.b", "/axb", false, nil}, // 正規表現の記号はそのまま比較する
+		{"/a+b", "/a+b", true, nil},
+		{"//seq", "/seq", true, nil}, // // は0以上の階層
+		{"//seq", "/1/fader1/seq", true, nil},
+		{"//seq", "/1/fader1/seqs", false, nil},
+		{"/1//*", "/1/a/b", true, []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.address, func(t *testing.T) {
+			re, err := compileOSCPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compileOSCPattern(%q) error = %v", tt.pattern, err)
+			}
+			m := re.FindStringSubmatch(tt.address)
+			if (m != nil) != tt.match {
+				t.Fatalf("match(%q, %q) = %v, want %v", tt.pattern, tt.address, m != nil, tt.match)
+			}
+			if !tt.match {
+				return
+			}
+			if len(m)-1 != len(tt.groups) {
+				t.Fatalf("groups = %q, want %q", m[1:], tt.groups)
+			}
+			for i, g := range tt.groups {
+				if m[i+1] != g {
+					t.Errorf("group %d = %q, want %q", i+1, m[i+1], g)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileOSCPatternErrors(t *testing.T) {
+	for _, pattern := range []string{"/fader[1-3", "/{fader,knob"} {
+		if _, err := compileOSCPattern(pattern); err == nil {
+			t.Errorf("compileOSCPattern(%q) error = nil, want error", pattern)
+		}
+		if matchOSCPattern(pattern, "/fader1") {
+			t.Errorf("matchOSCPattern(%q) = true for an invalid pattern", pattern)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// pcapのリンクタイプ
+const (
+	linkTypeNull     = 0   // BSDループバック（4バイトのアドレスファミリ、ホストバイトオーダー）
+	linkTypeEthernet = 1   // Ethernet
+	linkTypeRaw      = 101 // ヘッダーなしのIPv4/IPv6
+	linkTypeLoop     = 108 // OpenBSDループバック（ネットワークバイトオーダー）
+	linkTypeLinuxSLL = 113 // Linux cooked capture v1
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+	linkTypeSLL2     = 276 // Linux cooked capture v2
+	linkTypeRawAlt1  = 12  // 一部のOSでのヘッダーなしIP（DLT_RAW）
+	linkTypeRawAlt2  = 14  // OpenBSDでのヘッダーなしIP（DLT_RAW）
+)
+
+// リンク層ヘッダーの長さとEtherType
+const (
+	ethernetHeaderLen    = 14 // 宛先MAC(6) + 送信元MAC(6) + EtherType(2)
+	ethernetTypeOffset   = 12 // EtherTypeの位置
+	vlanTagLen           = 4  // 802.1Qタグ（TPID(2) + TCI(2)）
+	loopbackHeaderLen    = 4  // BSDループバックのアドレスファミリ
+	linuxSLLHeaderLen    = 16
+	linuxSLL2HeaderLen   = 20
+	etherTypeIPv4        = 0x0800
+	etherTypeIPv6        = 0x86DD
+	etherTypeVLAN        = 0x8100 // 802.1Q
+	etherTypeVLANStacked = 0x88A8 // 802.1ad
+)
+
+// pcapngのブロックタイプ
+const (
+	pcapngSectionHeader   = 0x0A0D0D0A
+	pcapngInterfaceDesc   = 0x00000001
+	pcapngObsoletePacket  = 0x00000002
+	pcapngSimplePacket    = 0x00000003
+	pcapngEnhancedPacket  = 0x00000006
+	pcapngByteOrderMagic  = 0x1A2B3C4D
+	pcapngOptionEnd       = 0
+	pcapngOptionTSResol   = 9
+	pcapMagicMicroseconds = 0xA1B2C3D4
+	pcapMagicNanoseconds  = 0xA1B23C4D
+)
+
+// CapturedPacket キャプチャファイルから読み込んだ1フレーム
+type CapturedPacket struct {
+	Time     time.Time
+	LinkType uint32
+	Data     []byte
+}
+
+// UDPDatagram フレームから取り出したUDPペイロード
+type UDPDatagram struct {
+	Time        time.Time
+	Source      netip.AddrPort
+	Destination netip.AddrPort
+	Payload     []byte
+}
+
+// CaptureImportResult キャプチャ読み込みの集計
+type CaptureImportResult struct {
+	Messages []OSCMessage // 古い順
+	Frames   int          // 読み込んだフレーム数
+	Datagram int          // 対象ポートのUDPデータグラム数
+	Invalid  int          // OSCとして解析できなかったデータグラム数
+}
+
+// ReadCapture pcapまたはpcapngファイルを読み込む
+func ReadCapture(r io.Reader) ([]CapturedPacket, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the capture file header: %w", err)
+	}
+
+	if binary.LittleEndian.Uint32(magic) == pcapngSectionHeader {
+		return readPcapng(br)
+	}
+	return readPcap(br)
+}
+
+// readPcap 従来のpcap形式を読み込む
+func readPcap(r io.Reader) ([]CapturedPacket, error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("cannot read the pcap header: %w", err)
+	}
+
+	var order binary.ByteOrder
+	var nano bool
+	switch {
+	case binary.LittleEndian.Uint32(header) == pcapMagicMicroseconds:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == pcapMagicMicroseconds:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == pcapMagicNanoseconds:
+		order, nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header) == pcapMagicNanoseconds:
+		order, nano = binary.BigEndian, true
+	default:
+		return nil, errors.New("not a pcap/pcapng file")
+	}
+	// 下位16ビットがリンクタイプ（上位はFCS情報）
+	linkType := order.Uint32(header[20:24]) & 0xFFFF
+
+	var packets []CapturedPacket
+	record := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF {
+				return packets, nil
+			}
+			return packets, fmt.Errorf("cannot read a pcap record: %w", err)
+		}
+		sec := order.Uint32(record[0:4])
+		frac := order.Uint32(record[4:8])
+		capLen := order.Uint32(record[8:12])
+		if capLen > 256*1024 {
+			return packets, fmt.Errorf("invalid pcap record size: %d", capLen)
+		}
+
+		data := make([]byte, capLen)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return packets, fmt.Errorf("cannot read a pcap record: %w", err)
+		}
+
+		nsec := int64(frac) * 1000
+		if nano {
+			nsec = int64(frac)
+		}
+		packets = append(packets, CapturedPacket{
+			Time:     time.Unix(int64(sec), nsec),
+			LinkType: linkType,
+			Data:     data,
+		})
+	}
+}
+
+// pcapngInterface pcapngのインターフェース情報
+type pcapngInterface struct {
+	linkType uint32
+	// tsUnit タイムスタンプ1単位の長さ（秒）
+	tsUnit float64
+}
+
+// readPcapng pcapng形式を読み込む
+func readPcapng(r io.Reader) ([]CapturedPacket, error) {
+	var packets []CapturedPacket
+	var order binary.ByteOrder = binary.LittleEndian
+	var interfaces []pcapngInterface
+
+	head := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, head); err != nil {
+			if err == io.EOF {
+				return packets, nil
+			}
+			return packets, fmt.Errorf("cannot read a pcapng block: %w", err)
+		}
+
+		blockType := order.Uint32(head[0:4])
+		if binary.LittleEndian.Uint32(head[0:4]) == pcapngSectionHeader {
+			// セクションごとにバイトオーダーとインターフェースがリセットされる
+			interfaces = nil
+			bom := make([]byte, 4)
+			if _, err := io.ReadFull(r, bom); err != nil {
+				return packets, fmt.Errorf("cannot read the pcapng section header: %w", err)
+			}
+			switch {
+			case binary.LittleEndian.Uint32(bom) == pcapngByteOrderMagic:
+				order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom) == pcapngByteOrderMagic:
+				order = binary.BigEndian
+			default:
+				return packets, errors.New("invalid pcapng byte order")
+			}
+			totalLen := order.Uint32(head[4:8])
+			if totalLen < 28 || totalLen%4 != 0 {
+				return packets, fmt.Errorf("invalid pcapng block length: %d", totalLen)
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(totalLen)-12); err != nil {
+				return packets, fmt.Errorf("cannot read the pcapng section header: %w", err)
+			}
+			continue
+		}
+
+		totalLen := order.Uint32(head[4:8])
+		if totalLen < 12 || totalLen%4 != 0 || totalLen > 16*1024*1024 {
+			return packets, fmt.Errorf("invalid pcapng block length: %d", totalLen)
+		}
+		body := make([]byte, totalLen-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return packets, fmt.Errorf("cannot read a pcapng block: %w", err)
+		}
+		body = body[:len(body)-4] // 末尾のブロック長を除く
+
+		switch blockType {
+		case pcapngInterfaceDesc:
+			if len(body) < 8 {
+				return packets, errors.New("pcapng interface description block is too short")
+			}
+			iface := pcapngInterface{
+				linkType: uint32(order.Uint16(body[0:2])),
+				tsUnit:   1e-6,
+			}
+			forEachPcapngOption(order, body[8:], func(code uint16, value []byte) {
+				if code == pcapngOptionTSResol && len(value) >= 1 {
+					if value[0]&0x80 != 0 {
+						iface.tsUnit = math.Pow(2, -float64(value[0]&0x7F))
+					} else {
+						iface.tsUnit = math.Pow(10, -float64(value[0]))
+					}
+				}
+			})
+			interfaces = append(interfaces, iface)
+
+		case pcapngEnhancedPacket, pcapngObsoletePacket:
+			if len(body) < 20 {
+				return packets, errors.New("pcapng packet block is too short")
+			}
+			ifaceID := int(order.Uint32(body[0:4]))
+			if blockType == pcapngObsoletePacket {
+				ifaceID = int(order.Uint16(body[0:2]))
+			}
+			if ifaceID >= len(interfaces) {
+				return packets, fmt.Errorf("undefined pcapng interface: %d", ifaceID)
+			}
+			iface := interfaces[ifaceID]
+			ts := uint64(order.Uint32(body[4:8]))<<32 | uint64(order.Uint32(body[8:12]))
+			capLen := order.Uint32(body[12:16])
+			if int(capLen) > len(body)-20 {
+				return packets, fmt.Errorf("invalid pcapng packet length: %d", capLen)
+			}
+			packets = append(packets, CapturedPacket{
+				Time:     pcapngTimestamp(ts, iface.tsUnit),
+				LinkType: iface.linkType,
+				Data:     append([]byte(nil), body[20:20+capLen]...),
+			})
+
+		case pcapngSimplePacket:
+			// タイムスタンプを持たず、常に最初のインターフェースに属する
+			if len(body) < 4 || len(interfaces) == 0 {
+				return packets, errors.New("invalid pcapng simple packet block")
+			}
+			data := body[4:]
+			if origLen := int(order.Uint32(body[0:4])); origLen < len(data) {
+				data = data[:origLen] // パディングを除く
+			}
+			packets = append(packets, CapturedPacket{
+				LinkType: interfaces[0].linkType,
+				Data:     append([]byte(nil), data...),
+			})
+		}
+	}
+}
+
+// forEachPcapngOption pcapngのオプション列を順に処理する
+func forEachPcapngOption(order binary.ByteOrder, options []byte, fn func(code uint16, value []byte)) {
+	for len(options) >= 4 {
+		code := order.Uint16(options[0:2])
+		length := int(order.Uint16(options[2:4]))
+		if code == pcapngOptionEnd {
+			return
+		}
+		padded := (length + 3) &^ 3
+		if 4+padded > len(options) {
+			return
+		}
+		fn(code, options[4:4+length])
+		options = options[4+padded:]
+	}
+}
+
+// pcapngTimestamp pcapngのタイムスタンプを時刻に変換する
+func pcapngTimestamp(ts uint64, unit float64) time.Time {
+	perSecond := uint64(math.Round(1 / unit))
+	if perSecond == 0 {
+		return time.Time{}
+	}
+	sec := ts / perSecond
+	frac := ts % perSecond
+	return time.Unix(int64(sec), int64(float64(frac)*unit*1e9))
+}
+
+// DecodeUDP フレームからIPv4/IPv6のUDPデータグラムを取り出す
+func DecodeUDP(packet CapturedPacket) (UDPDatagram, bool) {
+	ip, ok := linkPayload(packet.LinkType, packet.Data)
+	if !ok || len(ip) < 1 {
+		return UDPDatagram{}, false
+	}
+
+	var src, dst netip.Addr
+	var udp []byte
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 {
+			return UDPDatagram{}, false
+		}
+		ihl := int(ip[0]&0x0F) * 4
+		totalLen := int(binary.BigEndian.Uint16(ip[2:4]))
+		fragment := binary.BigEndian.Uint16(ip[6:8])
+		// 先頭以外のフラグメントはUDPヘッダーを持たない
+		if ip[9] != 17 || fragment&0x1FFF != 0 || ihl < 20 || len(ip) < ihl {
+			return UDPDatagram{}, false
+		}
+		if totalLen >= ihl && totalLen < len(ip) {
+			ip = ip[:totalLen] // Ethernetのパディングを除く
+		}
+		src = netip.AddrFrom4([4]byte(ip[12:16]))
+		dst = netip.AddrFrom4([4]byte(ip[16:20]))
+		udp = ip[ihl:]
+	case 6:
+		if len(ip) < 40 {
+			return UDPDatagram{}, false
+		}
+		src = netip.AddrFrom16([16]byte(ip[8:24]))
+		dst = netip.AddrFrom16([16]byte(ip[24:40]))
+		next := ip[6]
+		rest := ip[40:]
+		// Hop-by-Hop / Routing / Destination Options の拡張ヘッダーを読み飛ばす
+		for next == 0 || next == 43 || next == 60 {
+			if len(rest) < 8 {
+				return UDPDatagram{}, false
+			}
+			hdrLen := (int(rest[1]) + 1) * 8
+			if len(rest) < hdrLen {
+				return UDPDatagram{}, false
+			}
+			next = rest[0]
+			rest = rest[hdrLen:]
+		}
+		if next != 17 {
+			return UDPDatagram{}, false
+		}
+		udp = rest
+	default:
+		return UDPDatagram{}, false
+	}
+
+	if len(udp) < 8 {
+		return UDPDatagram{}, false
+	}
+	srcPort := binary.BigEndian.Uint16(udp[0:2])
+	dstPort := binary.BigEndian.Uint16(udp[2:4])
+	length := int(binary.BigEndian.Uint16(udp[4:6]))
+	payload := udp[8:]
+	if length >= 8 && length-8 < len(payload) {
+		payload = payload[:length-8]
+	}
+
+	return UDPDatagram{
+		Time:        packet.Time,
+		Source:      netip.AddrPortFrom(src.Unmap(), srcPort),
+		Destination: netip.AddrPortFrom(dst.Unmap(), dstPort),
+		Payload:     payload,
+	}, true
+}
+
+// linkPayload リンク層ヘッダーを取り除いてIPパケットを返す
+func linkPayload(linkType uint32, data []byte) ([]byte, bool) {
+	switch linkType {
+	case linkTypeEthernet:
+		if len(data) < ethernetHeaderLen {
+			return nil, false
+		}
+		etherType := binary.BigEndian.Uint16(data[ethernetTypeOffset:ethernetHeaderLen])
+		data = data[ethernetHeaderLen:]
+		// VLANタグ（802.1Q / 802.1ad）を読み飛ばす
+		for etherType == etherTypeVLAN || etherType == etherTypeVLANStacked {
+			if len(data) < vlanTagLen {
+				return nil, false
+			}
+			etherType = binary.BigEndian.Uint16(data[2:vlanTagLen])
+			data = data[vlanTagLen:]
+		}
+		if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+			return nil, false
+		}
+		return data, true
+	case linkTypeNull, linkTypeLoop:
+		if len(data) < loopbackHeaderLen {
+			return nil, false
+		}
+		return data[loopbackHeaderLen:], true
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6, linkTypeRawAlt1, linkTypeRawAlt2:
+		return data, true
+	case linkTypeLinuxSLL:
+		if len(data) < linuxSLLHeaderLen {
+			return nil, false
+		}
+		return data[linuxSLLHeaderLen:], true
+	case linkTypeSLL2:
+		if len(data) < linuxSLL2HeaderLen {
+			return nil, false
+		}
+		return data[linuxSLL2HeaderLen:], true
+	}
+	return nil, false
+}
+
+// ImportCapture キャプチャファイルからOSCメッセージを取り出す
+// portsが空の場合はすべてのUDPデータグラムを対象にする
+func ImportCapture(filename string, ports []int) (*CaptureImportResult, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	packets, err := ReadCapture(f)
+	if err != nil && len(packets) == 0 {
+		return nil, err
+	}
+	// 途中で切れたキャプチャは読めたところまでを使う
+	if err != nil {
+		log.Printf("キャプチャファイルの読み込みを途中で終了しました: %v", err)
+	}
+
+	result := &CaptureImportResult{Frames: len(packets)}
+	for _, packet := range packets {
+		datagram, ok := DecodeUDP(packet)
+		if !ok || !matchPorts(ports, datagram) {
+			continue
+		}
+		result.Datagram++
+
+		parsed, err := osc.ParsePacket(string(datagram.Payload))
+		if err != nil {
+			result.Invalid++
+			continue
+		}
+		for _, msg := range flattenPacket(parsed) {
+			oscMsg := newOSCMessage(msg, datagram.Source.String(), datagram.Time)
+			oscMsg.Destination = datagram.Destination.String()
+			oscMsg.Packet = datagram.Payload
+			result.Messages = append(result.Messages, oscMsg)
+		}
+	}
+
+	sort.SliceStable(result.Messages, func(i, j int) bool {
+		return result.Messages[i].Time.Before(result.Messages[j].Time)
+	})
+	return result, nil
+}
+
+// matchPorts データグラムの送信元か宛先のポートが一覧に含まれるか判定する
+func matchPorts(ports []int, datagram UDPDatagram) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, port := range ports {
+		if int(datagram.Source.Port()) == port || int(datagram.Destination.Port()) == port {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortList カンマ区切りのポート番号一覧を解析する（空文字は空の一覧）
+func parsePortList(text string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		port, err := parsePort(field)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// parsePort ポート番号（1〜65535）を読み取る
+func parsePort(text string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port number (1-65535): %s", text)
+	}
+	return port, nil
+}
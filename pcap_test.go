@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// testFrame 192.168.1.10:50000 → 192.168.1.20:7000 のUDPを載せたEthernetフレームを作成する（チェックサムは0）
+func testFrame(payload []byte) []byte {
+	frame := []byte{
+		0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 1, 0x08, 0x00, // Ethernet（IPv4）
+		0x45, 0, 0, 0, 0, 0, 0x40, 0, 64, 17, 0, 0, // IPv4（全長は下で入れる）、UDP
+		192, 168, 1, 10,
+		192, 168, 1, 20,
+		0xC3, 0x50, 0x1B, 0x58, 0, 0, 0, 0, // UDP 50000 → 7000
+	}
+	binary.BigEndian.PutUint16(frame[16:18], uint16(20+8+len(payload)))
+	binary.BigEndian.PutUint16(frame[38:40], uint16(8+len(payload)))
+	return append(frame, payload...)
+}
+
+// testPcapngBlock pcapngのブロックを1つ作成する
+func testPcapngBlock(blockType uint32, body []byte) []byte {
+	body = append(body, make([]byte, (4-len(body)%4)%4)...)
+	var b []byte
+	b = binary.LittleEndian.AppendUint32(b, blockType)
+	b = binary.LittleEndian.AppendUint32(b, uint32(12+len(body)))
+	b = append(b, body...)
+	return binary.LittleEndian.AppendUint32(b, uint32(12+len(body)))
+}
+
+// testPcapng 1フレームのpcapngファイルを作成する（タイムスタンプはマイクロ秒単位）
+func testPcapng(micros uint64, frame []byte) []byte {
+	le := binary.LittleEndian
+	shb := le.AppendUint32(nil, pcapngByteOrderMagic)
+	shb = le.AppendUint16(shb, 1)
+	shb = le.AppendUint16(shb, 0)
+	shb = le.AppendUint64(shb, 0xFFFFFFFFFFFFFFFF)
+
+	idb := le.AppendUint16(nil, linkTypeEthernet)
+	idb = le.AppendUint16(idb, 0)
+	idb = le.AppendUint32(idb, 0)
+
+	epb := le.AppendUint32(nil, 0)
+	epb = le.AppendUint32(epb, uint32(micros>>32))
+	epb = le.AppendUint32(epb, uint32(micros))
+	epb = le.AppendUint32(epb, uint32(len(frame)))
+	epb = le.AppendUint32(epb, uint32(len(frame)))
+	epb = append(epb, frame...)
+
+	b := testPcapngBlock(pcapngSectionHeader, shb)
+	b = append(b, testPcapngBlock(pcapngInterfaceDesc, idb)...)
+	return append(b, testPcapngBlock(pcapngEnhancedPacket, epb)...)
+}
+
+// testPcap 1フレームの従来のpcapファイルを作成する
+func testPcap(order binary.AppendByteOrder, magic uint32, frac uint32, frame []byte) []byte {
+	var b []byte
+	b = order.AppendUint32(b, magic)
+	b = order.AppendUint16(b, 2)
+	b = order.AppendUint16(b, 4)
+	b = append(b, make([]byte, 8)...) // thiszone, sigfigs
+	b = order.AppendUint32(b, 65535)
+	b = order.AppendUint32(b, linkTypeEthernet)
+
+	b = order.AppendUint32(b, 1700000000)
+	b = order.AppendUint32(b, frac)
+	b = order.AppendUint32(b, uint32(len(frame)))
+	b = order.AppendUint32(b, uint32(len(frame)))
+	return append(b, frame...)
+}
+
+func TestReadCapture(t *testing.T) {
+	src := netip.MustParseAddrPort("192.168.1.10:50000")
+	dst := netip.MustParseAddrPort("192.168.1.20:7000")
+	payload := []byte("/ping\x00\x00\x00,\x00\x00\x00")
+	frame := testFrame(payload)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		time    time.Time
+		wantErr bool
+	}{
+		{"pcap little endian", testPcap(binary.LittleEndian, pcapMagicMicroseconds, 250000, frame), time.Unix(1700000000, 250000000), false},
+		{"pcap big endian", testPcap(binary.BigEndian, pcapMagicMicroseconds, 250000, frame), time.Unix(1700000000, 250000000), false},
+		{"pcap nanoseconds", testPcap(binary.LittleEndian, pcapMagicNanoseconds, 123, frame), time.Unix(1700000000, 123), false},
+		{"pcapng", testPcapng(1700000000123456, frame), time.Unix(1700000000, 123456000), false},
+		{"not a capture", []byte("hello world, this is not a capture file"), time.Time{}, true},
+		{"too short", []byte{1, 2}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packets, err := ReadCapture(bytes.NewReader(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ReadCapture() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadCapture() error = %v", err)
+			}
+			if len(packets) != 1 {
+				t.Fatalf("ReadCapture() = %d packets, want 1", len(packets))
+			}
+			if !packets[0].Time.Equal(tt.time) {
+				t.Errorf("Time = %v, want %v", packets[0].Time, tt.time)
+			}
+
+			datagram, ok := DecodeUDP(packets[0])
+			if !ok {
+				t.Fatal("DecodeUDP() failed")
+			}
+			if datagram.Source != src || datagram.Destination != dst {
+				t.Errorf("endpoints = %v -> %v, want %v -> %v", datagram.Source, datagram.Destination, src, dst)
+			}
+			if !bytes.Equal(datagram.Payload, payload) {
+				t.Errorf("Payload = %q, want %q", datagram.Payload, payload)
+			}
+		})
+	}
+}
+
+func TestReadCaptureTruncated(t *testing.T) {
+	frame := testFrame([]byte("/a\x00\x00,\x00\x00\x00"))
+	data := testPcap(binary.LittleEndian, pcapMagicMicroseconds, 0, frame)
+	data = append(data, data[24:len(data)-4]...) // 2つ目のフレームは途中で切れている
+
+	packets, err := ReadCapture(bytes.NewReader(data))
+	if err == nil {
+		t.Error("ReadCapture() error = nil, want error for truncated record")
+	}
+	if len(packets) != 1 {
+		t.Errorf("ReadCapture() = %d packets, want the 1 complete packet", len(packets))
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"time"
+)
+
+// 合成するEthernetヘッダーのMACアドレス（ローカル管理アドレス）
+var (
+	pcapngSourceMAC      = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	pcapngDestinationMAC = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+// maxUDPPayload UDPで送れるペイロードの最大サイズ（IPv4）
+const maxUDPPayload = 65507
+
+// PcapngWriter OSCメッセージをEthernet/IP/UDPヘッダー付きのpcapngとして書き出す
+type PcapngWriter struct {
+	w io.Writer
+}
+
+// NewPcapngWriter セクションヘッダーとインターフェース記述を書き込んでWriterを作成する
+func NewPcapngWriter(w io.Writer) (*PcapngWriter, error) {
+	pw := &PcapngWriter{w: w}
+
+	// Section Header Block（セクション長は不明のため-1）
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:4], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:6], 1)
+	binary.LittleEndian.PutUint16(shb[6:8], 0)
+	binary.LittleEndian.PutUint64(shb[8:16], 0xFFFFFFFFFFFFFFFF)
+	if err := pw.writeBlock(pcapngSectionHeader, shb); err != nil {
+		return nil, err
+	}
+
+	// Interface Description Block（Ethernet、タイムスタンプはナノ秒単位）
+	idb := make([]byte, 8, 20)
+	binary.LittleEndian.PutUint16(idb[0:2], linkTypeEthernet)
+	binary.LittleEndian.PutUint32(idb[4:8], 0) // snaplen無制限
+	idb = binary.LittleEndian.AppendUint16(idb, pcapngOptionTSResol)
+	idb = binary.LittleEndian.AppendUint16(idb, 1)
+	idb = append(idb, 9, 0, 0, 0)
+	idb = binary.LittleEndian.AppendUint32(idb, pcapngOptionEnd)
+	if err := pw.writeBlock(pcapngInterfaceDesc, idb); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// writeBlock ブロック長と4バイト境界のパディングを付けてブロックを書き込む
+func (pw *PcapngWriter) writeBlock(blockType uint32, body []byte) error {
+	padded := (len(body) + 3) &^ 3
+	total := uint32(12 + padded)
+
+	block := make([]byte, 0, total)
+	block = binary.LittleEndian.AppendUint32(block, blockType)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	block = append(block, body...)
+	block = append(block, make([]byte, padded-len(body))...)
+	block = binary.LittleEndian.AppendUint32(block, total)
+
+	_, err := pw.w.Write(block)
+	return err
+}
+
+// messagePayload メッセージのUDPペイロードを返す
+// 受信したペイロードがあればそのまま使い、なければ（セッションファイルなど）メッセージから組み立てる
+func messagePayload(msg OSCMessage) ([]byte, error) {
+	if len(msg.Packet) > 0 {
+		return msg.Packet, nil
+	}
+	oscMsg, err := buildOSCMessage(msg.Address, msg.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msg.Address, err)
+	}
+	payload, err := oscMsg.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msg.Address, err)
+	}
+	if len(payload) > maxUDPPayload {
+		return nil, fmt.Errorf("%s: payload too large (%d bytes)", msg.Address, len(payload))
+	}
+	return payload, nil
+}
+
+// WriteMessage メッセージを1フレームとして書き込む
+func (pw *PcapngWriter) WriteMessage(msg OSCMessage) error {
+	payload, err := messagePayload(msg)
+	if err != nil {
+		return err
+	}
+	return pw.WritePacket(msg.Time, msg.Source, msg.Destination, payload)
+}
+
+// WritePacket UDPペイロードを1フレームとして書き込む
+func (pw *PcapngWriter) WritePacket(t time.Time, source, destination string, payload []byte) error {
+	src, dst := pcapngEndpoints(source, destination)
+	frame := buildUDPFrame(src, dst, payload)
+
+	ts := uint64(t.UnixNano())
+	epb := make([]byte, 20, 20+len(frame))
+	binary.LittleEndian.PutUint32(epb[0:4], 0) // インターフェースID
+	binary.LittleEndian.PutUint32(epb[4:8], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(epb[8:12], uint32(ts))
+	binary.LittleEndian.PutUint32(epb[12:16], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(epb[16:20], uint32(len(frame)))
+	epb = append(epb, frame...)
+	return pw.writeBlock(pcapngEnhancedPacket, epb)
+}
+
+// pcapngEndpoints 送信元・宛先アドレスを解析する。不明な場合はループバックで補い、
+// IPv4とIPv6が混在する場合はIPv4射影アドレスでIPv6にそろえる
+func pcapngEndpoints(source, destination string) (netip.AddrPort, netip.AddrPort) {
+	parse := func(s string) netip.AddrPort {
+		ap, err := netip.ParseAddrPort(s)
+		if err != nil {
+			return netip.AddrPortFrom(netip.AddrFrom4([4]byte{127, 0, 0, 1}), 0)
+		}
+		return netip.AddrPortFrom(ap.Addr().Unmap().WithZone(""), ap.Port())
+	}
+	src, dst := parse(source), parse(destination)
+
+	if src.Addr().Is4() != dst.Addr().Is4() {
+		src = netip.AddrPortFrom(netip.AddrFrom16(src.Addr().As16()), src.Port())
+		dst = netip.AddrPortFrom(netip.AddrFrom16(dst.Addr().As16()), dst.Port())
+	}
+	return src, dst
+}
+
+// buildUDPFrame Ethernet/IP/UDPヘッダーを合成したフレームを作成する
+func buildUDPFrame(src, dst netip.AddrPort, payload []byte) []byte {
+	udp := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], src.Port())
+	binary.BigEndian.PutUint16(udp[2:4], dst.Port())
+	binary.BigEndian.PutUint16(udp[4:6], uint16(8+len(payload)))
+	udp = append(udp, payload...)
+
+	srcIP := src.Addr().AsSlice()
+	dstIP := dst.Addr().AsSlice()
+
+	// UDPチェックサム（疑似ヘッダー込み）
+	pseudo := append(append([]byte{}, srcIP...), dstIP...)
+	pseudo = append(pseudo, 0, 17)
+	pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(udp)))
+	sum := internetChecksum(append(pseudo, udp...))
+	if sum == 0 {
+		sum = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(udp[6:8], sum)
+
+	var ip []byte
+	etherType := uint16(etherTypeIPv4)
+	if src.Addr().Is4() {
+		ip = make([]byte, 20)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(udp)))
+		binary.BigEndian.PutUint16(ip[6:8], 0x4000) // Don't Fragment
+		ip[8] = 64
+		ip[9] = 17
+		copy(ip[12:16], srcIP)
+		copy(ip[16:20], dstIP)
+		binary.BigEndian.PutUint16(ip[10:12], internetChecksum(ip))
+	} else {
+		etherType = etherTypeIPv6
+		ip = make([]byte, 40)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:6], uint16(len(udp)))
+		ip[6] = 17
+		ip[7] = 64
+		copy(ip[8:24], srcIP)
+		copy(ip[24:40], dstIP)
+	}
+
+	frame := make([]byte, 0, ethernetHeaderLen+len(ip)+len(udp))
+	frame = append(frame, pcapngDestinationMAC...)
+	frame = append(frame, pcapngSourceMAC...)
+	frame = binary.BigEndian.AppendUint16(frame, etherType)
+	frame = append(frame, ip...)
+	return append(frame, udp...)
+}
+
+// internetChecksum RFC 1071のチェックサムを計算する
+func internetChecksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// exportPcapng メッセージをpcapngとして書き出す。変換できないメッセージは読み飛ばす
+// 同じバンドルから展開したメッセージは、受信したパケットを1回だけ書き出す
+func exportPcapng(w io.Writer, msgs []OSCMessage) error {
+	pw, err := NewPcapngWriter(w)
+	if err != nil {
+		return err
+	}
+	written := map[*byte]bool{}
+	for _, msg := range msgs {
+		if len(msg.Packet) > 0 {
+			if written[&msg.Packet[0]] {
+				continue
+			}
+			written[&msg.Packet[0]] = true
+		}
+		payload, err := messagePayload(msg)
+		if err != nil {
+			log.Printf("pcapng書き出しをスキップ: %v", err)
+			continue
+		}
+		if err := pw.WritePacket(msg.Time, msg.Source, msg.Destination, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
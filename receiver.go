@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// OSCReceiver UDPでOSCパケットを受信し、送信元アドレスと受信時刻付きで通知する
+// osc.Serverでは送信元アドレスが取得できないため独自に受信ループを持つ
+type OSCReceiver struct {
+	conn      net.PacketConn
+	onMessage func(msg OSCMessage)
+}
+
+// StartOSCReceiver 指定アドレスで受信を開始する
+func StartOSCReceiver(addr string, onMessage func(msg OSCMessage)) (*OSCReceiver, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &OSCReceiver{
+		conn:      conn,
+		onMessage: onMessage,
+	}
+	go r.serve()
+	return r, nil
+}
+
+// serve 接続が閉じられるまでパケットを受信し続ける
+func (r *OSCReceiver) serve() {
+	data := make([]byte, 65535)
+	for {
+		n, source, err := r.conn.ReadFrom(data)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("OSC受信エラー: %v", err)
+			}
+			return
+		}
+		received := time.Now()
+
+		packet, err := osc.ParsePacket(string(data[:n]))
+		if err != nil {
+			log.Printf("OSCパケット解析エラー [%s]: %v", source, err)
+			continue
+		}
+
+		// 受信バッファは使い回すので、書き出し用にペイロードをコピーしておく
+		payload := append([]byte(nil), data[:n]...)
+
+		// バンドルはタイムタグを待たずに受信時刻で展開する
+		for _, msg := range flattenPacket(packet) {
+			oscMsg := newOSCMessage(msg, source.String(), received)
+			oscMsg.Destination = r.LocalAddr()
+			oscMsg.Packet = payload
+			r.onMessage(oscMsg)
+		}
+	}
+}
+
+// LocalAddr 受信中のローカルアドレスを返す
+func (r *OSCReceiver) LocalAddr() string {
+	return r.conn.LocalAddr().String()
+}
+
+// Close 受信を停止する
+func (r *OSCReceiver) Close() error {
+	return r.conn.Close()
+}
+
+// newOSCMessage 受信したOSCメッセージを表示用の構造体に変換する
+func newOSCMessage(msg *osc.Message, source string, received time.Time) OSCMessage {
+	// 引数を文字列に変換
+	var values []string
+	for _, arg := range msg.Arguments {
+		values = append(values, fmt.Sprintf("%v", arg))
+	}
+
+	return OSCMessage{
+		Timestamp: received.Format("15:04:05"),
+		Address:   msg.Address,
+		Values:    strings.Join(values, ", "),
+		Time:      received,
+		Source:    source,
+		Arguments: oscArgumentsFromValues(msg.Arguments),
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// ReplayOptions セッション再生の設定
+type ReplayOptions struct {
+	Host   string
+	Port   int
+	Speed  float64       // 再生速度の倍率（1.0=等速）
+	Loop   bool          // 最後まで再生したら先頭から繰り返す
+	Start  time.Duration // セッション先頭からの再生開始位置
+	End    time.Duration // セッション先頭からの再生終了位置（0=最後まで）
+	Filter string        // アドレスフィルター（受信側と同じ書式）
+}
+
+// replayItem 再生対象のレコードと再生開始からのオフセット
+type replayItem struct {
+	record SessionRecord
+	offset time.Duration
+}
+
+// Replayer セッションファイルのメッセージを元のタイミングで再送信する
+type Replayer struct {
+	items []replayItem
+	opts  ReplayOptions
+
+	// OnProgress 1件送信するごとに呼ばれる（sent: 今回のループでの送信数）
+	OnProgress func(sent, total, loop int)
+
+	mu      sync.Mutex
+	paused  bool
+	base    time.Time
+	stateCh chan struct{}
+	stopCh  chan struct{}
+	stopped sync.Once
+}
+
+// NewReplayer 再生範囲とフィルターを適用してReplayerを作成する
+func NewReplayer(records []SessionRecord, opts ReplayOptions) (*Replayer, error) {
+	if opts.Host == "" || opts.Port <= 0 {
+		return nil, errors.New("enter the host and port to replay to")
+	}
+	if opts.Speed <= 0 {
+		return nil, fmt.Errorf("invalid speed: %g", opts.Speed)
+	}
+	if opts.End != 0 && opts.End <= opts.Start {
+		return nil, errors.New("the end must be after the start")
+	}
+
+	var items []replayItem
+	if len(records) > 0 {
+		first := records[0].Time
+		for _, record := range records {
+			pos := record.Time.Sub(first)
+			if pos < opts.Start || (opts.End != 0 && pos > opts.End) {
+				continue
+			}
+			if !matchAddressFilter(opts.Filter, record.Address) {
+				continue
+			}
+			items = append(items, replayItem{
+				record: record,
+				offset: time.Duration(float64(pos-opts.Start) / opts.Speed),
+			})
+		}
+	}
+	if len(items) == 0 {
+		return nil, errors.New("no messages to replay")
+	}
+
+	return &Replayer{
+		items:   items,
+		opts:    opts,
+		stateCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// Total 1ループあたりの送信件数を返す
+func (r *Replayer) Total() int {
+	return len(r.items)
+}
+
+// Run 再生を行う。停止されるか最後まで再生する（ループ時は停止される）まで戻らない
+func (r *Replayer) Run() error {
+	client := osc.NewClient(r.opts.Host, r.opts.Port)
+
+	for loop := 1; ; loop++ {
+		r.base = time.Now()
+		for i, item := range r.items {
+			if !r.waitUntil(item.offset) {
+				return nil
+			}
+
+			msg, err := buildOSCMessage(item.record.Address, item.record.Arguments)
+			if err != nil {
+				return fmt.Errorf("%s: %w", item.record.Address, err)
+			}
+			if err := client.Send(msg); err != nil {
+				return err
+			}
+
+			if r.OnProgress != nil {
+				r.OnProgress(i+1, len(r.items), loop)
+			}
+		}
+		if !r.opts.Loop {
+			return nil
+		}
+	}
+}
+
+// waitUntil 再生開始からoffset経過するまで待つ。一時停止中の時間は基準時刻をずらして除外する
+// 停止された場合はfalseを返す
+func (r *Replayer) waitUntil(offset time.Duration) bool {
+	for {
+		if r.IsPaused() {
+			pausedAt := time.Now()
+			select {
+			case <-r.stateCh:
+				r.base = r.base.Add(time.Since(pausedAt))
+				continue
+			case <-r.stopCh:
+				return false
+			}
+		}
+
+		wait := time.Until(r.base.Add(offset))
+		if wait <= 0 {
+			return true
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+			return true
+		case <-r.stateCh:
+			timer.Stop()
+		case <-r.stopCh:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// notify 再生ループに状態変化を知らせる
+func (r *Replayer) notify() {
+	select {
+	case r.stateCh <- struct{}{}:
+	default:
+	}
+}
+
+// Pause 一時停止する
+func (r *Replayer) Pause() {
+	r.mu.Lock()
+	r.paused = true
+	r.mu.Unlock()
+	r.notify()
+}
+
+// Resume 一時停止を解除する
+func (r *Replayer) Resume() {
+	r.mu.Lock()
+	r.paused = false
+	r.mu.Unlock()
+	r.notify()
+}
+
+// IsPaused 一時停止中かどうかを返す
+func (r *Replayer) IsPaused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paused
+}
+
+// Stop 再生を停止する
+func (r *Replayer) Stop() {
+	r.stopped.Do(func() {
+		close(r.stopCh)
+	})
+}
+
+// createReplayWindow セッション再生ウィンドウを作成
+func createReplayWindow(a fyne.App, target SenderTarget, updateHistory func(string)) fyne.Window {
+	win := a.NewWindow("Session Replay")
+
+	fileEntry := widget.NewEntry()
+	fileEntry.SetPlaceHolder("Session file (.jsonl)")
+
+	browseBtn := widget.NewButton("Browse...", func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			fileEntry.SetText(reader.URI().Path())
+		}, win)
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".jsonl"}))
+		fileDialog.Show()
+	})
+
+	hostEntry := widget.NewEntry()
+	hostEntry.SetText(target.Host)
+	hostEntry.SetPlaceHolder("Host IP")
+
+	portEntry := widget.NewEntry()
+	portEntry.SetText(fmt.Sprintf("%d", target.Port))
+	portEntry.SetPlaceHolder("Port")
+
+	speedEntry := widget.NewEntry()
+	speedEntry.SetText("1.0")
+
+	loopCheck := widget.NewCheck("Loop", nil)
+
+	startEntry := widget.NewEntry()
+	startEntry.SetPlaceHolder("0")
+
+	endEntry := widget.NewEntry()
+	endEntry.SetPlaceHolder("end")
+
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Address Filter (e.g. /test*, empty=all)")
+
+	progressBar := widget.NewProgressBar()
+	statusLabel := widget.NewLabel("Stopped")
+
+	var replayer *Replayer
+	var playBtn, pauseBtn, stopBtn *widget.Button
+
+	// 再生状態に合わせてボタンを切り替える
+	setPlaying := func(playing bool) {
+		if playing {
+			playBtn.Disable()
+			pauseBtn.Enable()
+			stopBtn.Enable()
+		} else {
+			playBtn.Enable()
+			pauseBtn.Disable()
+			pauseBtn.SetText("Pause")
+			stopBtn.Disable()
+		}
+	}
+
+	playBtn = widget.NewButton("Play", func() {
+		records, err := LoadSession(fileEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+
+		port, err := strconv.Atoi(portEntry.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid port number: %s", portEntry.Text), win)
+			return
+		}
+		speed, err := strconv.ParseFloat(speedEntry.Text, 64)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("invalid speed: %s", speedEntry.Text), win)
+			return
+		}
+		start, err := durationFromSeconds(startEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		end, err := durationFromSeconds(endEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+
+		opts := ReplayOptions{
+			Host:   hostEntry.Text,
+			Port:   port,
+			Speed:  speed,
+			Loop:   loopCheck.Checked,
+			Start:  start,
+			End:    end,
+			Filter: filterEntry.Text,
+		}
+		r, err := NewReplayer(records, opts)
+		if err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		r.OnProgress = func(sent, total, loop int) {
+			fyne.Do(func() {
+				progressBar.SetValue(float64(sent) / float64(total))
+				statusLabel.SetText(fmt.Sprintf("Playing: %d / %d (loop %d)", sent, total, loop))
+			})
+		}
+		replayer = r
+
+		progressBar.SetValue(0)
+		statusLabel.SetText(fmt.Sprintf("Playing: 0 / %d", r.Total()))
+		setPlaying(true)
+		log.Printf("セッション再生を開始: %s → %s:%d (%d件, x%g)", fileEntry.Text, opts.Host, opts.Port, r.Total(), opts.Speed)
+
+		go func() {
+			err := r.Run()
+			fyne.Do(func() {
+				if err != nil {
+					log.Printf("セッション再生エラー: %v", err)
+					statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				} else {
+					statusLabel.SetText("Stopped")
+				}
+				setPlaying(false)
+				timestamp := time.Now().Format("15:04:05")
+				updateHistory(fmt.Sprintf("%s | Replay → %s:%d %s", timestamp, opts.Host, opts.Port, fileEntry.Text))
+			})
+			log.Println("セッション再生を終了")
+		}()
+	})
+
+	pauseBtn = widget.NewButton("Pause", func() {
+		if replayer == nil {
+			return
+		}
+		if replayer.IsPaused() {
+			replayer.Resume()
+			pauseBtn.SetText("Pause")
+		} else {
+			replayer.Pause()
+			pauseBtn.SetText("Resume")
+			statusLabel.SetText("Paused")
+		}
+	})
+
+	stopBtn = widget.NewButton("Stop", func() {
+		if replayer != nil {
+			replayer.Stop()
+		}
+	})
+
+	setPlaying(false)
+
+	form := widget.NewForm(
+		widget.NewFormItem("Session", container.NewBorder(nil, nil, nil, browseBtn, fileEntry)),
+		widget.NewFormItem("Host", hostEntry),
+		widget.NewFormItem("Port", portEntry),
+		widget.NewFormItem("Speed", speedEntry),
+		widget.NewFormItem("Start (sec)", startEntry),
+		widget.NewFormItem("End (sec)", endEntry),
+		widget.NewFormItem("Filter", filterEntry),
+		widget.NewFormItem("", loopCheck),
+	)
+
+	win.SetContent(container.NewVBox(
+		widget.NewCard("Session Replay", "", nil),
+		form,
+		widget.NewSeparator(),
+		container.NewHBox(playBtn, pauseBtn, stopBtn, layout.NewSpacer()),
+		progressBar,
+		statusLabel,
+	))
+	win.Resize(fyne.NewSize(520, 480))
+	// 閉じても再利用できるよう非表示にする
+	win.SetCloseIntercept(func() {
+		if replayer != nil {
+			replayer.Stop()
+		}
+		win.Hide()
+	})
+	return win
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// compiledRewriteRule パターンをコンパイル済みの書き換えルール
+type compiledRewriteRule struct {
+	rule RewriteRule
+	re   *regexp.Regexp
+}
+
+// compileRewriteRules 書き換えルールを検証してコンパイルする
+// matchが空のルールはルートのパターンを使う
+func compileRewriteRules(rules []RewriteRule, routePattern string) ([]compiledRewriteRule, error) {
+	var compiled []compiledRewriteRule
+	for i, rule := range rules {
+		pattern := rule.Match
+		if pattern == "" {
+			pattern = routePattern
+		}
+		re, err := compileOSCPattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		for j, mapping := range rule.Arguments {
+			if err := mapping.validate(); err != nil {
+				return nil, fmt.Errorf("rules[%d].arguments[%d]: %w", i, j, err)
+			}
+		}
+		compiled = append(compiled, compiledRewriteRule{rule: rule, re: re})
+	}
+	return compiled, nil
+}
+
+// validate 引数マッピングの設定を検証する
+func (m ArgumentMapping) validate() error {
+	if m.From < 0 {
+		return fmt.Errorf("from must be 1 or more: %d", m.From)
+	}
+	if m.From == 0 && m.Value == "" {
+		return errors.New("set either from or value")
+	}
+	switch m.Type {
+	case "", "int", "float", "string", "bool", "int64", "double":
+	default:
+		return fmt.Errorf("unsupported argument type: %s", m.Type)
+	}
+	if m.Scale != nil {
+		if m.Scale.In[0] == m.Scale.In[1] {
+			return errors.New("scale.in is an empty range")
+		}
+		switch m.Scale.Curve {
+		case "", "linear":
+		case "exp":
+			if m.Scale.Out[0] == 0 || m.Scale.Out[1] == 0 || (m.Scale.Out[0] > 0) != (m.Scale.Out[1] > 0) {
+				return errors.New("scale.out for the exp curve must be non-zero and of the same sign")
+			}
+		default:
+			return fmt.Errorf("unsupported curve: %s (linear, exp)", m.Scale.Curve)
+		}
+	}
+	return nil
+}
+
+// rewriteMessage 最初に一致したルールでアドレスと引数を書き換える
+// 一致するルールがなければそのまま返す
+func rewriteMessage(rules []compiledRewriteRule, address string, arguments []OSCArgument) (string, []OSCArgument, error) {
+	for _, compiled := range rules {
+		match := compiled.re.FindStringSubmatchIndex(address)
+		if match == nil {
+			continue
+		}
+
+		newAddress := address
+		if compiled.rule.Address != "" {
+			// $1, ${2} をパターンのワイルドカードに一致した部分で置き換える
+			newAddress = string(compiled.re.ExpandString(nil, compiled.rule.Address, address, match))
+		}
+
+		if len(compiled.rule.Arguments) == 0 {
+			return newAddress, arguments, nil
+		}
+
+		newArguments := make([]OSCArgument, 0, len(compiled.rule.Arguments))
+		for i, mapping := range compiled.rule.Arguments {
+			arg, err := mapping.apply(arguments)
+			if err != nil {
+				return "", nil, fmt.Errorf("argument %d: %w", i+1, err)
+			}
+			newArguments = append(newArguments, arg)
+		}
+		return newAddress, newArguments, nil
+	}
+	return address, arguments, nil
+}
+
+// apply 入力引数から出力引数を1つ作る（選択 → スケーリング → 型変換）
+func (m ArgumentMapping) apply(arguments []OSCArgument) (OSCArgument, error) {
+	var arg OSCArgument
+	if m.From > 0 {
+		if m.From > len(arguments) {
+			return OSCArgument{}, fmt.Errorf("the message has no argument %d", m.From)
+		}
+		arg = arguments[m.From-1]
+	} else {
+		arg = OSCArgument{Type: "string", Value: m.Value}
+		if m.Type != "" {
+			arg.Type = m.Type
+		}
+	}
+
+	if m.Scale != nil {
+		value, err := argumentNumber(arg)
+		if err != nil {
+			return OSCArgument{}, err
+		}
+		originalType := arg.Type
+		arg = OSCArgument{Type: "double", Value: strconv.FormatFloat(m.Scale.apply(value), 'g', -1, 64)}
+		if m.Type == "" {
+			// 型指定がなければ元の型に戻す
+			return castArgument(arg, originalType)
+		}
+	}
+
+	if m.Type != "" {
+		return castArgument(arg, m.Type)
+	}
+	return arg, nil
+}
+
+// apply 値を入力範囲から出力範囲へ変換する
+func (s ArgumentScale) apply(value float64) float64 {
+	t := (value - s.In[0]) / (s.In[1] - s.In[0])
+	if s.Clamp {
+		t = math.Max(0, math.Min(1, t))
+	}
+	if s.Curve == "exp" {
+		// 出力範囲を指数的（等比）に補間する
+		return s.Out[0] * math.Pow(s.Out[1]/s.Out[0], t)
+	}
+	return s.Out[0] + (s.Out[1]-s.Out[0])*t
+}
+
+// argumentNumber 引数を数値として読み取る（boolは1/0）
+func argumentNumber(arg OSCArgument) (float64, error) {
+	switch arg.Type {
+	case "bool":
+		val, err := strconv.ParseBool(arg.Value)
+		if err != nil {
+			return 0, fmt.Errorf("not a valid bool: %q", arg.Value)
+		}
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	case "blob", "nil", "timetag":
+		return 0, fmt.Errorf("a %s argument cannot be used as a number", arg.Type)
+	}
+	val, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("not a number: %q", arg.Value)
+	}
+	return val, nil
+}
+
+// castArgument 引数を指定の型に変換する
+func castArgument(arg OSCArgument, typ string) (OSCArgument, error) {
+	if arg.Type == typ {
+		return arg, nil
+	}
+
+	switch typ {
+	case "string":
+		return OSCArgument{Type: "string", Value: arg.Value}, nil
+	case "bool":
+		if val, err := strconv.ParseBool(arg.Value); err == nil {
+			return OSCArgument{Type: "bool", Value: strconv.FormatBool(val)}, nil
+		}
+		num, err := argumentNumber(arg)
+		if err != nil {
+			return OSCArgument{}, err
+		}
+		return OSCArgument{Type: "bool", Value: strconv.FormatBool(num != 0)}, nil
+	}
+
+	num, err := argumentNumber(arg)
+	if err != nil {
+		return OSCArgument{}, err
+	}
+	switch typ {
+	case "int":
+		// 範囲外の値を折り返して別の値にしない
+		n := math.Round(num)
+		if n < math.MinInt32 || n > math.MaxInt32 || math.IsNaN(n) {
+			return OSCArgument{}, fmt.Errorf("%s is out of range for int (use int64)", arg.Value)
+		}
+		return OSCArgument{Type: "int", Value: strconv.FormatInt(int64(n), 10)}, nil
+	case "int64":
+		n := math.Round(num)
+		if n < math.MinInt64 || n >= math.MaxInt64 || math.IsNaN(n) {
+			return OSCArgument{}, fmt.Errorf("%s is out of range for int64", arg.Value)
+		}
+		return OSCArgument{Type: "int64", Value: strconv.FormatInt(int64(n), 10)}, nil
+	case "float":
+		return OSCArgument{Type: "float", Value: strconv.FormatFloat(num, 'g', -1, 32)}, nil
+	case "double":
+		return OSCArgument{Type: "double", Value: strconv.FormatFloat(num, 'g', -1, 64)}, nil
+	}
+	return OSCArgument{}, fmt.Errorf("unsupported argument type: %s", typ)
+}
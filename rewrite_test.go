@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestArgumentScaleApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		scale ArgumentScale
+		value float64
+		want  float64
+	}{
+		{"linear", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{-60, 10}}, 0.5, -25},
+		{"linear reversed", ArgumentScale{In: [2]float64{0, 127}, Out: [2]float64{1, 0}}, 127, 0},
+		{"linear extrapolates", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{0, 100}}, 1.5, 150},
+		{"clamp high", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{0, 100}, Clamp: true}, 1.5, 100},
+		{"clamp low", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{0, 100}, Clamp: true}, -1, 0},
+		{"exp midpoint", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{20, 20000}, Curve: "exp"}, 0.5, math.Sqrt(20 * 20000)},
+		{"exp end", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{20, 20000}, Curve: "exp"}, 1, 20000},
+		{"exp negative", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{-1, -100}, Curve: "exp"}, 0.5, -10},
+		{"exp clamp", ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{1, 100}, Curve: "exp", Clamp: true}, 2, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scale.apply(tt.value); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("apply(%g) = %g, want %g", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCastArgument(t *testing.T) {
+	tests := []struct {
+		arg     OSCArgument
+		typ     string
+		want    string
+		wantErr string // 空ならエラーなし
+	}{
+		{OSCArgument{Type: "float", Value: "2.5"}, "int", "3", ""},
+		{OSCArgument{Type: "float", Value: "-2.5"}, "int", "-3", ""},
+		{OSCArgument{Type: "double", Value: "2147483647"}, "int", "2147483647", ""},
+		{OSCArgument{Type: "double", Value: "2147483648"}, "int", "", "out of range"},
+		{OSCArgument{Type: "double", Value: "-2147483649"}, "int", "", "out of range"},
+		{OSCArgument{Type: "double", Value: "2147483648"}, "int64", "2147483648", ""},
+		{OSCArgument{Type: "double", Value: "1e19"}, "int64", "", "out of range"},
+		{OSCArgument{Type: "int", Value: "3"}, "float", "3", ""},
+		{OSCArgument{Type: "double", Value: "0.1"}, "float", "0.1", ""},
+		{OSCArgument{Type: "int", Value: "3"}, "double", "3", ""},
+		{OSCArgument{Type: "int", Value: "0"}, "bool", "false", ""},
+		{OSCArgument{Type: "float", Value: "0.5"}, "bool", "true", ""},
+		{OSCArgument{Type: "string", Value: "true"}, "bool", "true", ""},
+		{OSCArgument{Type: "bool", Value: "true"}, "int", "1", ""},
+		{OSCArgument{Type: "int", Value: "42"}, "string", "42", ""},
+		{OSCArgument{Type: "string", Value: "abc"}, "int", "", "not a number"},
+		{OSCArgument{Type: "timetag", Value: "1"}, "int", "", "cannot be used as a number"},
+		{OSCArgument{Type: "int", Value: "1"}, "blob", "", "unsupported argument type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg.Type+" "+tt.arg.Value+" to "+tt.typ, func(t *testing.T) {
+			got, err := castArgument(tt.arg, tt.typ)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("castArgument() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("castArgument() error = %v", err)
+			}
+			if got.Type != tt.typ || got.Value != tt.want {
+				t.Errorf("castArgument() = %+v, want %s %q", got, tt.typ, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteMessage(t *testing.T) {
+	rules, err := compileRewriteRules([]RewriteRule{
+		{Match: "/1/fader*", Address: "/mixer/ch/$1/gain", Arguments: []ArgumentMapping{
+			{From: 1, Scale: &ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{-60, 10}}},
+		}},
+		{Match: "/1/toggle*", Address: "/mixer/ch/${1}/mute", Arguments: []ArgumentMapping{
+			{From: 1, Type: "bool"},
+			{Value: "on", Type: "string"},
+		}},
+		{Match: "/1/{push,xy}*", Address: "/button"}, // 引数はそのまま
+		{Match: "/2/*", Arguments: []ArgumentMapping{{From: 2}}},
+		{Match: "/1/*", Address: "/never"}, // 先に一致したルールだけ使う
+	}, "/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := func(values ...string) []OSCArgument {
+		var result []OSCArgument
+		for i := 0; i+1 < len(values); i += 2 {
+			result = append(result, OSCArgument{Type: values[i], Value: values[i+1]})
+		}
+		return result
+	}
+	tests := []struct {
+		address     string
+		arguments   []OSCArgument
+		wantAddress string
+		wantArgs    []OSCArgument
+		wantErr     bool
+	}{
+		{"/1/fader3", args("float", "0.5"), "/mixer/ch/3/gain", args("float", "-25"), false},
+		{"/1/toggle2", args("int", "1"), "/mixer/ch/2/mute", args("bool", "true", "string", "on"), false},
+		{"/1/push1", args("int", "1", "int", "2"), "/button", args("int", "1", "int", "2"), false},
+		{"/2/x", args("int", "1", "string", "b"), "/2/x", args("string", "b"), false},
+		{"/3/x", args("int", "1"), "/3/x", args("int", "1"), false}, // 一致しなければそのまま
+		{"/2/x", args("int", "1"), "", nil, true},                   // 入力引数2がない
+		{"/1/fader1", args("string", "loud"), "", nil, true},        // 数値にできない
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			address, arguments, err := rewriteMessage(rules, tt.address, tt.arguments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rewriteMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if address != tt.wantAddress {
+				t.Errorf("address = %q, want %q", address, tt.wantAddress)
+			}
+			if len(arguments) != len(tt.wantArgs) {
+				t.Fatalf("arguments = %+v, want %+v", arguments, tt.wantArgs)
+			}
+			for i := range arguments {
+				if arguments[i] != tt.wantArgs[i] {
+					t.Errorf("argument %d = %+v, want %+v", i+1, arguments[i], tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompileRewriteRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule RewriteRule
+	}{
+		{"bad pattern", RewriteRule{Match: "/a[1"}},
+		{"negative from", RewriteRule{Arguments: []ArgumentMapping{{From: -1}}}},
+		{"no from or value", RewriteRule{Arguments: []ArgumentMapping{{Type: "int"}}}},
+		{"unknown type", RewriteRule{Arguments: []ArgumentMapping{{From: 1, Type: "char"}}}},
+		{"empty input range", RewriteRule{Arguments: []ArgumentMapping{{From: 1, Scale: &ArgumentScale{In: [2]float64{1, 1}}}}}},
+		{"exp through zero", RewriteRule{Arguments: []ArgumentMapping{{From: 1, Scale: &ArgumentScale{In: [2]float64{0, 1}, Out: [2]float64{-1, 1}, Curve: "exp"}}}}},
+		{"unknown curve", RewriteRule{Arguments: []ArgumentMapping{{From: 1, Scale: &ArgumentScale{In: [2]float64{0, 1}, Curve: "log"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileRewriteRules([]RewriteRule{tt.rule}, "/*"); err == nil {
+				t.Error("compileRewriteRules() error = nil, want error")
+			}
+		})
+	}
+}
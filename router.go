@@ -0,0 +1,601 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hypebeast/go-osc/osc"
+	"gopkg.in/yaml.v3"
+)
+
+// routerQueueSize 転送待ちにできるメッセージの数。あふれた分は転送エラーとして数える
+const routerQueueSize = 1024
+
+// routeDestination 転送先。targetがあれば送るときに送信先リストからhost・portを引く
+// （カードでhost・portを変えてもすぐ転送先に反映される）
+type routeDestination struct {
+	target string
+	host   string
+	port   int
+}
+
+// String 転送先を送信先名または "host:port" 形式で返す
+func (d routeDestination) String() string {
+	if d.target != "" {
+		return d.target
+	}
+	return net.JoinHostPort(d.host, strconv.Itoa(d.port))
+}
+
+// routeJob 転送待ちのメッセージ
+type routeJob struct {
+	route *Route
+	msg   *osc.Message
+	local string // 受信したポートのアドレス（自分自身への転送を防ぐ）
+}
+
+// Route 転送ルールの実行時状態（カウンタは受信ゴルーチンから更新される）
+type Route struct {
+	config       RouteConfig
+	destinations []routeDestination
+	rules        []compiledRewriteRule
+
+	enabled   atomic.Bool
+	matched   atomic.Uint64
+	forwarded atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// newRoute 設定から転送ルールを作成する。Targetで指定された転送先は送信先リストから解決する
+func newRoute(cfg RouteConfig, targets []SenderTarget) (*Route, error) {
+	if _, err := compileOSCPattern(cfg.Pattern); err != nil {
+		return nil, err
+	}
+
+	rules, err := compileRewriteRules(cfg.Rules, cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("route %s: invalid rewrite rules: %w", cfg.Name, err)
+	}
+
+	route := &Route{config: cfg, rules: rules}
+	for _, dest := range cfg.Destinations {
+		if dest.Target != "" {
+			if _, ok := findSenderTarget(targets, dest.Target); !ok {
+				return nil, fmt.Errorf("route %s: target not found: %s", cfg.Name, dest.Target)
+			}
+			route.destinations = append(route.destinations, routeDestination{target: dest.Target})
+			continue
+		}
+		if dest.Host == "" || dest.Port <= 0 || dest.Port > 65535 {
+			return nil, fmt.Errorf("route %s: invalid destination: %s:%d", cfg.Name, dest.Host, dest.Port)
+		}
+		route.destinations = append(route.destinations, routeDestination{host: dest.Host, port: dest.Port})
+	}
+	route.enabled.Store(!cfg.Disabled)
+	return route, nil
+}
+
+// findSenderTarget 送信先リストから名前で送信先を探す
+func findSenderTarget(targets []SenderTarget, name string) (SenderTarget, bool) {
+	for _, target := range targets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	return SenderTarget{}, false
+}
+
+// Config ルールの設定を返す（有効/無効は現在の状態を反映）
+func (r *Route) Config() RouteConfig {
+	cfg := r.config
+	cfg.Disabled = !r.enabled.Load()
+	return cfg
+}
+
+// Enabled ルールが有効かどうかを返す
+func (r *Route) Enabled() bool {
+	return r.enabled.Load()
+}
+
+// SetEnabled ルールの有効/無効を切り替える
+func (r *Route) SetEnabled(enabled bool) {
+	r.enabled.Store(enabled)
+}
+
+// Counters 一致数、転送数、転送エラー数を返す
+func (r *Route) Counters() (matched, forwarded, failed uint64) {
+	return r.matched.Load(), r.forwarded.Load(), r.failed.Load()
+}
+
+// ResetCounters カウンタをリセットする
+func (r *Route) ResetCounters() {
+	r.matched.Store(0)
+	r.forwarded.Store(0)
+	r.failed.Store(0)
+}
+
+// transform 書き換えルールを適用して送信するメッセージを作成する
+func (r *Route) transform(msg OSCMessage) (*osc.Message, error) {
+	address, arguments, err := rewriteMessage(r.rules, msg.Address, msg.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	return buildOSCMessage(address, arguments)
+}
+
+// Router 受信したメッセージを転送ルールに従って転送する
+// 送信は転送用のゴルーチンで行い、遅い転送先があっても受信を止めない
+type Router struct {
+	mu      sync.RWMutex
+	routes  []*Route
+	targets []SenderTarget
+
+	queue chan routeJob
+
+	// version ルール一式を読み込み直すたびに増える（表示の更新用）
+	version atomic.Uint64
+}
+
+// NewRouter 設定からRouterを作成する。無効なルールはログに出力して読み飛ばす
+func NewRouter(settings RouterSettings, targets []SenderTarget) *Router {
+	router := &Router{queue: make(chan routeJob, routerQueueSize)}
+	go router.forwardLoop()
+	if err := router.Load(settings, targets); err != nil {
+		log.Printf("転送ルールを読み込めません: %v", err)
+	}
+	return router
+}
+
+// Load ルールを設定から読み込み直す。無効なルールは読み飛ばしてエラーを返す
+func (r *Router) Load(settings RouterSettings, targets []SenderTarget) error {
+	var routes []*Route
+	var errs []error
+	for _, cfg := range settings.Routes {
+		route, err := newRoute(cfg, targets)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	r.mu.Lock()
+	r.routes = routes
+	r.targets = append([]SenderTarget(nil), targets...)
+	r.mu.Unlock()
+	r.version.Add(1)
+	return errors.Join(errs...)
+}
+
+// SetTargets 転送先の送信先名を引く送信先リストを入れ替える（カードの編集に追従する）
+func (r *Router) SetTargets(targets []SenderTarget) {
+	r.mu.Lock()
+	r.targets = append([]SenderTarget(nil), targets...)
+	r.mu.Unlock()
+}
+
+// resolve 転送先のhost・portを返す
+func (r *Router) resolve(dest routeDestination) (string, int, error) {
+	if dest.target == "" {
+		return dest.host, dest.port, nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	target, ok := findSenderTarget(r.targets, dest.target)
+	if !ok {
+		return "", 0, fmt.Errorf("target not found: %s", dest.target)
+	}
+	return target.Host, target.Port, nil
+}
+
+// Version ルール一式を読み込み直した回数
+func (r *Router) Version() uint64 {
+	return r.version.Load()
+}
+
+// Routes 転送ルールの一覧を返す
+func (r *Router) Routes() []*Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Route(nil), r.routes...)
+}
+
+// Settings 現在の転送ルールを設定として返す
+func (r *Router) Settings() RouterSettings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var settings RouterSettings
+	for _, route := range r.routes {
+		settings.Routes = append(settings.Routes, route.Config())
+	}
+	return settings
+}
+
+// RenameTarget 送信先の名前の変更に合わせて、その名前を指す転送先を書き換える。カウンタと有効/無効は引き継ぐ
+func (r *Router) RenameTarget(oldName, newName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	renamed := false
+	for i, route := range r.routes {
+		cfg := route.Config()
+		cfg.Destinations = append([]RouteDestination(nil), cfg.Destinations...)
+		destinations := append([]routeDestination(nil), route.destinations...)
+		changed := false
+		for j := range cfg.Destinations {
+			if cfg.Destinations[j].Target == oldName {
+				cfg.Destinations[j].Target = newName
+				changed = true
+			}
+		}
+		for j := range destinations {
+			if destinations[j].target == oldName {
+				destinations[j].target = newName
+			}
+		}
+		if !changed {
+			continue
+		}
+		next := &Route{config: cfg, destinations: destinations, rules: route.rules}
+		next.enabled.Store(route.enabled.Load())
+		next.matched.Store(route.matched.Load())
+		next.forwarded.Store(route.forwarded.Load())
+		next.failed.Store(route.failed.Load())
+		r.routes[i] = next
+		renamed = true
+	}
+	if renamed {
+		r.version.Add(1)
+	}
+}
+
+// AddRoute ルールを追加する
+func (r *Router) AddRoute(route *Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route)
+}
+
+// ReplaceRoute ルールを置き換える。カウンタは引き継ぐ
+func (r *Router) ReplaceRoute(old, route *Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.routes {
+		if existing == old {
+			route.matched.Store(old.matched.Load())
+			route.forwarded.Store(old.forwarded.Load())
+			route.failed.Store(old.failed.Load())
+			r.routes[i] = route
+			return
+		}
+	}
+}
+
+// RemoveRoute ルールを削除する
+func (r *Router) RemoveRoute(route *Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.routes {
+		if existing == route {
+			r.routes = append(r.routes[:i], r.routes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Handle 受信メッセージに一致する有効なルールの転送を、転送待ちに追加する
+func (r *Router) Handle(msg OSCMessage) {
+	for _, route := range r.Routes() {
+		if !route.Enabled() || !matchOSCPattern(route.config.Pattern, msg.Address) {
+			continue
+		}
+		route.matched.Add(1)
+
+		// 書き換えルールを適用してから送信する
+		oscMsg, err := route.transform(msg)
+		if err != nil {
+			route.failed.Add(uint64(len(route.destinations)))
+			log.Printf("転送エラー [%s]: %v", route.config.Name, err)
+			continue
+		}
+
+		select {
+		case r.queue <- routeJob{route: route, msg: oscMsg, local: msg.Destination}:
+		default:
+			// 転送が追いつかないときは捨ててエラーとして数える
+			route.failed.Add(uint64(len(route.destinations)))
+		}
+	}
+}
+
+// forwardLoop 転送待ちのメッセージを順番に送信する
+func (r *Router) forwardLoop() {
+	for job := range r.queue {
+		r.forward(job)
+	}
+}
+
+// forward 1つのルールの転送先へ送信する
+// 受信ポート自身への転送はループになるため行わない
+func (r *Router) forward(job routeJob) {
+	route := job.route
+	for _, dest := range route.destinations {
+		host, port, err := r.resolve(dest)
+		if err == nil {
+			var addr *net.UDPAddr
+			if addr, err = net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port))); err == nil && isSelfDestination(addr, job.local) {
+				// 自分自身への転送はエラーとして数える
+				route.failed.Add(1)
+				continue
+			}
+		}
+		if err == nil {
+			err = osc.NewClient(host, port).Send(job.msg)
+		}
+		if err != nil {
+			route.failed.Add(1)
+			log.Printf("転送エラー [%s → %s]: %v", route.config.Name, dest, err)
+			continue
+		}
+		route.forwarded.Add(1)
+	}
+}
+
+// isSelfDestination 転送先が受信ポート自身かどうか
+// localhostと127.0.0.1、0.0.0.0で待ち受けているときの自分のIPアドレスなども同じとみなす
+func isSelfDestination(dest *net.UDPAddr, local string) bool {
+	localAddr, err := net.ResolveUDPAddr("udp", local)
+	if err != nil || dest.Port != localAddr.Port {
+		return false
+	}
+	switch {
+	case dest.IP.Equal(localAddr.IP):
+		return true
+	case localAddr.IP == nil || localAddr.IP.IsUnspecified():
+		return isLocalIP(dest.IP)
+	default:
+		return dest.IP.IsLoopback() && localAddr.IP.IsLoopback()
+	}
+}
+
+// isLocalIP このマシンのIPアドレスかどうか
+func isLocalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatRouteDestinations 転送先を "host:port" または送信先名のカンマ区切りにする
+func formatRouteDestinations(destinations []RouteDestination) string {
+	var parts []string
+	for _, dest := range destinations {
+		if dest.Target != "" {
+			parts = append(parts, dest.Target)
+		} else {
+			parts = append(parts, net.JoinHostPort(dest.Host, strconv.Itoa(dest.Port)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseRouteDestinations "host:port" または送信先名のカンマ区切りを解析する
+func parseRouteDestinations(text string) ([]RouteDestination, error) {
+	var destinations []RouteDestination
+	for _, field := range strings.Split(text, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(field)
+		if err != nil {
+			// host:port でなければ送信先名として扱う
+			destinations = append(destinations, RouteDestination{Target: field})
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port number: %s", field)
+		}
+		destinations = append(destinations, RouteDestination{Host: host, Port: port})
+	}
+	if len(destinations) == 0 {
+		return nil, errors.New("add at least one destination")
+	}
+	return destinations, nil
+}
+
+// createRouterWindow 転送ルールの編集ウィンドウを作成
+// onChange はルールが変更されるたびに呼ばれる（設定への反映用）
+func createRouterWindow(a fyne.App, router *Router, targets func() []SenderTarget, onChange func()) fyne.Window {
+	win := a.NewWindow("OSC Router")
+
+	routesContainer := container.NewVBox()
+	counterLabels := map[*Route]*widget.Label{}
+
+	// カウンタ表示を更新する関数
+	updateCounters := func() {
+		for route, label := range counterLabels {
+			matched, forwarded, failed := route.Counters()
+			label.SetText(fmt.Sprintf("matched %d / forwarded %d / failed %d", matched, forwarded, failed))
+		}
+	}
+
+	var updateRoutesDisplay func()
+
+	// ルール編集ダイアログを表示する関数（routeがnilなら新規追加）
+	showRouteForm := func(route *Route) {
+		nameEntry := widget.NewEntry()
+		patternEntry := widget.NewEntry()
+		patternEntry.SetPlaceHolder("/1/* or /mixer/{fader,mute}/?")
+		destEntry := widget.NewEntry()
+		destEntry.SetPlaceHolder("127.0.0.1:9001, Remote Device")
+		rulesEntry := widget.NewMultiLineEntry()
+		rulesEntry.SetPlaceHolder("- address: \"/mixer/ch/$1/gain\"\n  arguments:\n    - from: 1\n      scale: {in: [0, 1], out: [-60, 10]}")
+		rulesEntry.SetMinRowsVisible(6)
+
+		title := "New Route"
+		if route != nil {
+			title = "Edit Route"
+			cfg := route.Config()
+			nameEntry.SetText(cfg.Name)
+			patternEntry.SetText(cfg.Pattern)
+			destEntry.SetText(formatRouteDestinations(cfg.Destinations))
+			if len(cfg.Rules) > 0 {
+				if data, err := yaml.Marshal(cfg.Rules); err == nil {
+					rulesEntry.SetText(string(data))
+				}
+			}
+		}
+
+		dialog.ShowForm(title, "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Name", nameEntry),
+			widget.NewFormItem("Pattern", patternEntry),
+			widget.NewFormItem("Destinations", destEntry),
+			widget.NewFormItem("Rules (YAML)", rulesEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			destinations, err := parseRouteDestinations(destEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			var rules []RewriteRule
+			if err := yaml.Unmarshal([]byte(rulesEntry.Text), &rules); err != nil {
+				dialog.ShowError(fmt.Errorf("cannot parse the rewrite rules: %w", err), win)
+				return
+			}
+			cfg := RouteConfig{
+				Name:         nameEntry.Text,
+				Pattern:      patternEntry.Text,
+				Destinations: destinations,
+				Rules:        rules,
+			}
+			if route != nil {
+				cfg.Disabled = !route.Enabled()
+			}
+			updated, err := newRoute(cfg, targets())
+			if err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+
+			if route != nil {
+				router.ReplaceRoute(route, updated)
+			} else {
+				router.AddRoute(updated)
+			}
+			updateRoutesDisplay()
+			onChange()
+		}, win)
+	}
+
+	updateRoutesDisplay = func() {
+		routesContainer.RemoveAll()
+		counterLabels = map[*Route]*widget.Label{}
+
+		for _, route := range router.Routes() {
+			r := route // クロージャ用
+			cfg := r.Config()
+
+			enabledCheck := widget.NewCheck(cfg.Name, func(checked bool) {
+				r.SetEnabled(checked)
+				if checked {
+					log.Printf("転送ルールを有効化: %s", cfg.Name)
+				} else {
+					log.Printf("転送ルールを無効化: %s", cfg.Name)
+				}
+				onChange()
+			})
+			enabledCheck.SetChecked(r.Enabled())
+
+			counterLabel := widget.NewLabel("")
+			counterLabels[r] = counterLabel
+
+			editBtn := widget.NewButton("Edit", func() {
+				showRouteForm(r)
+			})
+			resetBtn := widget.NewButton("Reset", func() {
+				r.ResetCounters()
+				updateCounters()
+			})
+			removeBtn := widget.NewButton("✕", func() {
+				router.RemoveRoute(r)
+				updateRoutesDisplay()
+				onChange()
+			})
+
+			routesContainer.Add(widget.NewCard("", "", container.NewVBox(
+				container.NewHBox(enabledCheck, layout.NewSpacer(), editBtn, resetBtn, removeBtn),
+				widget.NewLabel(fmt.Sprintf("%s → %s (%d rules)", cfg.Pattern, formatRouteDestinations(cfg.Destinations), len(cfg.Rules))),
+				counterLabel,
+			)))
+		}
+		updateCounters()
+		routesContainer.Refresh()
+	}
+
+	updateRoutesDisplay()
+
+	addRouteBtn := widget.NewButton("＋", func() {
+		showRouteForm(nil)
+	})
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewCard("OSC Router", "Forward received messages matching an address pattern", nil),
+			container.NewHBox(addRouteBtn),
+		),
+		nil, nil, nil,
+		container.NewScroll(routesContainer),
+	))
+	win.Resize(fyne.NewSize(600, 450))
+
+	// 閉じても再利用できるよう非表示にする
+	win.SetCloseIntercept(func() {
+		win.Hide()
+	})
+
+	// カウンタを定期的に更新する（設定が読み込み直されたら一覧も作り直す）
+	shownVersion := router.Version()
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			fyne.Do(func() {
+				if v := router.Version(); v != shownVersion {
+					shownVersion = v
+					updateRoutesDisplay()
+					return
+				}
+				updateCounters()
+			})
+		}
+	}()
+
+	return win
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsSelfDestination(t *testing.T) {
+	tests := []struct {
+		dest  string
+		local string
+		want  bool
+	}{
+		{"127.0.0.1:9000", "127.0.0.1:9000", true},
+		{"localhost:9000", "127.0.0.1:9000", true},
+		{"127.0.0.2:9000", "127.0.0.1:9000", true}, // ループバック同士
+		{"127.0.0.1:9000", "0.0.0.0:9000", true},
+		{"[::1]:9000", "[::]:9000", true},
+		{"127.0.0.1:9001", "127.0.0.1:9000", false},
+		{"192.0.2.10:9000", "127.0.0.1:9000", false},
+		{"192.0.2.10:9000", "0.0.0.0:9000", false}, // このマシンのアドレスではない
+		{"127.0.0.1:9000", "", false},              // 受信ポートが不明
+	}
+	for _, tt := range tests {
+		t.Run(tt.dest+" "+tt.local, func(t *testing.T) {
+			dest, err := net.ResolveUDPAddr("udp", tt.dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := isSelfDestination(dest, tt.local); got != tt.want {
+				t.Errorf("isSelfDestination(%s, %q) = %v, want %v", tt.dest, tt.local, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterResolvesTargetsWhenForwarding(t *testing.T) {
+	targets := []SenderTarget{{Name: "Mixer", Host: "10.0.0.1", Port: 9000}}
+	router := NewRouter(RouterSettings{Routes: []RouteConfig{{
+		Name:         "To Mixer",
+		Pattern:      "/*",
+		Destinations: []RouteDestination{{Target: "Mixer"}},
+	}}}, targets)
+	dest := router.Routes()[0].destinations[0]
+
+	// カードでhost・portを変えたら次の転送から使われる
+	router.SetTargets([]SenderTarget{{Name: "Mixer", Host: "10.0.0.2", Port: 9001}})
+	host, port, err := router.resolve(dest)
+	if err != nil || host != "10.0.0.2" || port != 9001 {
+		t.Errorf("resolve() = %s, %d, %v, want 10.0.0.2, 9001", host, port, err)
+	}
+
+	router.SetTargets(nil)
+	if _, _, err := router.resolve(dest); err == nil {
+		t.Error("resolve() error = nil for a removed target")
+	}
+}
+
+func TestRouterRenameTarget(t *testing.T) {
+	targets := []SenderTarget{{Name: "Mixer", Host: "10.0.0.1", Port: 9000}}
+	router := NewRouter(RouterSettings{Routes: []RouteConfig{{
+		Name:         "To Mixer",
+		Pattern:      "/*",
+		Destinations: []RouteDestination{{Target: "Mixer"}, {Host: "10.0.0.9", Port: 8000}},
+		Disabled:     true,
+	}}}, targets)
+	router.Routes()[0].matched.Store(3)
+
+	router.SetTargets([]SenderTarget{{Name: "Desk", Host: "10.0.0.1", Port: 9000}})
+	router.RenameTarget("Mixer", "Desk")
+
+	route := router.Routes()[0]
+	if got := route.Config().Destinations[0].Target; got != "Desk" {
+		t.Errorf("destination target = %q, want Desk", got)
+	}
+	if host, port, err := router.resolve(route.destinations[0]); err != nil || host != "10.0.0.1" || port != 9000 {
+		t.Errorf("resolve() = %s, %d, %v, want 10.0.0.1, 9000", host, port, err)
+	}
+	// 保存し直しても読み込める
+	if err := router.Load(router.Settings(), []SenderTarget{{Name: "Desk", Host: "10.0.0.1", Port: 9000}}); err != nil {
+		t.Errorf("Load() after rename error = %v", err)
+	}
+	route = router.Routes()[0]
+	if route.Enabled() {
+		t.Error("rename enabled a disabled route")
+	}
+}
@@ -0,0 +1,841 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hypebeast/go-osc/osc"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// ScriptConfig 送受信を行うLuaスクリプト
+type ScriptConfig struct {
+	Name      string `yaml:"name"`
+	File      string `yaml:"file"`                // 設定ファイルのディレクトリからの相対パス
+	Autostart bool   `yaml:"autostart,omitempty"` // 設定ファイルを読み込んだら実行する
+}
+
+// scriptEventBuffer スクリプトが処理待ちにできるイベント（受信・タイマー）の数。あふれた分は捨てる
+const scriptEventBuffer = 256
+
+// scriptPath スクリプトファイルのパスを設定ファイルの場所から決める
+func scriptPath(configFile, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(filepath.Dir(configFile), file)
+}
+
+// compileScript スクリプトを読み込んで構文を確認する
+// 構文エラーは*parse.Error（行・列つき）で返す
+func compileScript(path string) (*lua.FunctionProto, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	chunk, err := parse.Parse(f, path)
+	if err != nil {
+		return nil, err
+	}
+	return lua.Compile(chunk, path)
+}
+
+// ScriptLabel スクリプトが osc.label で表示する値
+type ScriptLabel struct {
+	Name string
+	Text string
+}
+
+// scriptEvent スクリプトのゴルーチンで実行する処理（受信・タイマー）
+type scriptEvent func(rt *scriptRuntime) error
+
+// scriptSubscription osc.on で登録した受信時の処理
+type scriptSubscription struct {
+	pattern string
+	fn      *lua.LFunction
+}
+
+// Script 1つのスクリプトの実行状態
+type Script struct {
+	config ScriptConfig
+	path   string
+	engine *ScriptEngine
+
+	mu       sync.Mutex
+	running  bool
+	stopping bool
+	status   string
+	sent     int
+	labels   []ScriptLabel
+	subs     map[int]scriptSubscription
+	events   chan scriptEvent
+	stop     chan struct{}
+	done     chan struct{}
+	cancel   context.CancelFunc
+}
+
+// Config スクリプトの設定を返す
+func (s *Script) Config() ScriptConfig {
+	return s.config
+}
+
+// Path スクリプトファイルのパスを返す
+func (s *Script) Path() string {
+	return s.path
+}
+
+// Status 実行中かどうかと状態の表示（Stopped, Running, Finished, Error: ...）を返す
+func (s *Script) Status() (running bool, status string, sent int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running, s.status, s.sent
+}
+
+// Labels スクリプトが表示している値を返す
+func (s *Script) Labels() []ScriptLabel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ScriptLabel(nil), s.labels...)
+}
+
+// scriptLibs スクリプトに開くLuaの標準ライブラリ（io・os・package・debugは開かない）
+var scriptLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// newScriptState ファイルやプロセスに触れられないLuaの状態を作成する
+func newScriptState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range scriptLibs {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// baseライブラリに含まれるファイルの読み込みも使えなくする
+	for _, name := range []string{"dofile", "loadfile", "require", "module"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
+
+// Start スクリプトファイルを読み込んで実行を始める（実行中なら何もしない）
+func (s *Script) Start() error {
+	proto, err := compileScript(s.path)
+	if err != nil {
+		return fmt.Errorf("cannot load script %s: %w", s.config.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil
+	}
+
+	L := newScriptState()
+	ctx, cancel := context.WithCancel(context.Background())
+	L.SetContext(ctx)
+
+	s.running, s.stopping = true, false
+	s.status, s.sent, s.labels = "Running", 0, nil
+	s.subs = map[int]scriptSubscription{}
+	s.events = make(chan scriptEvent, scriptEventBuffer)
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.cancel = cancel
+
+	rt := &scriptRuntime{script: s, L: L, timers: map[int]func(){}, clients: map[string]*osc.Client{}}
+	rt.register()
+	go rt.run(L.NewFunctionFromProto(proto), s.events, s.stop, s.done)
+
+	log.Printf("スクリプト %s を開始しました: %s", s.config.Name, s.path)
+	s.engine.changed()
+	return nil
+}
+
+// Stop 実行を止め、終わるまで待つ
+func (s *Script) Stop() {
+	s.mu.Lock()
+	if !s.running || s.stopping {
+		done := s.done
+		s.mu.Unlock()
+		if done != nil {
+			<-done
+		}
+		return
+	}
+	s.stopping = true
+	close(s.stop)
+	s.cancel()
+	done := s.done
+	s.mu.Unlock()
+	<-done
+}
+
+// post スクリプトのゴルーチンで実行する処理を追加する（処理待ちがあふれたら捨てる）
+func (s *Script) post(ev scriptEvent) {
+	s.mu.Lock()
+	events, stop := s.events, s.stop
+	s.mu.Unlock()
+	if events == nil {
+		return
+	}
+	select {
+	case <-stop:
+	case events <- ev:
+	default:
+		log.Printf("スクリプト %s: 処理が追いつかないためイベントを捨てました", s.config.Name)
+	}
+}
+
+// handle 受信したメッセージを、一致する osc.on の処理に渡す
+func (s *Script) handle(msg OSCMessage) {
+	s.mu.Lock()
+	var ids []int
+	if s.running && !s.stopping {
+		for id, sub := range s.subs {
+			if matchOSCPattern(sub.pattern, msg.Address) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	s.post(func(rt *scriptRuntime) error {
+		for _, id := range ids {
+			s.mu.Lock()
+			sub, ok := s.subs[id]
+			s.mu.Unlock()
+			if !ok {
+				// 処理待ちの間に osc.off された
+				continue
+			}
+			if err := rt.call(sub.fn, rt.message(msg)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// finish 実行が終わったときの状態にする
+func (s *Script) finish(err error) {
+	s.mu.Lock()
+	switch {
+	case s.stopping:
+		s.status = "Stopped"
+	case err != nil:
+		log.Printf("スクリプト %s でエラーが発生しました: %v", s.config.Name, err)
+		// 表示はスタックトレースを除いたメッセージだけにする
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) {
+			s.status = fmt.Sprintf("Error: %s", apiErr.Object.String())
+		} else {
+			s.status = fmt.Sprintf("Error: %v", err)
+		}
+	default:
+		s.status = "Finished"
+	}
+	s.running, s.stopping = false, false
+	s.subs = nil
+	s.events = nil
+	s.cancel()
+	s.mu.Unlock()
+
+	log.Printf("スクリプト %s を終了しました", s.config.Name)
+	s.engine.changed()
+}
+
+// scriptRuntime 実行中のスクリプトの状態（スクリプトのゴルーチンだけが使う）
+type scriptRuntime struct {
+	script  *Script
+	L       *lua.LState
+	timers  map[int]func() // タイマーID → 止める関数
+	nextID  int
+	clients map[string]*osc.Client
+}
+
+// run メインチャンクを実行し、受信やタイマーの処理を順番に実行する
+// 受信の登録もタイマーもなくなったら終わる
+func (rt *scriptRuntime) run(fn *lua.LFunction, events <-chan scriptEvent, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	err := rt.call(fn)
+loop:
+	for err == nil && !rt.idle() {
+		select {
+		case <-stop:
+			break loop
+		case ev := <-events:
+			err = ev(rt)
+		}
+	}
+
+	for _, stopTimer := range rt.timers {
+		stopTimer()
+	}
+	rt.L.Close()
+	rt.script.finish(err)
+}
+
+// idle 待っているもの（受信の登録・タイマー）がないかどうか
+func (rt *scriptRuntime) idle() bool {
+	rt.script.mu.Lock()
+	defer rt.script.mu.Unlock()
+	return len(rt.timers) == 0 && len(rt.script.subs) == 0
+}
+
+// call Luaの関数を呼ぶ
+func (rt *scriptRuntime) call(fn *lua.LFunction, args ...lua.LValue) error {
+	return rt.L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...)
+}
+
+// newID 受信の登録・タイマーのIDを作る
+func (rt *scriptRuntime) newID() int {
+	rt.nextID++
+	return rt.nextID
+}
+
+// register Luaから使う osc テーブルを登録する
+func (rt *scriptRuntime) register() {
+	L := rt.L
+	api := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"send":   rt.luaSend,
+		"reply":  rt.luaReply,
+		"on":     rt.luaOn,
+		"off":    rt.luaOff,
+		"after":  func(L *lua.LState) int { return rt.luaTimer(L, false) },
+		"every":  func(L *lua.LState) int { return rt.luaTimer(L, true) },
+		"cancel": rt.luaCancel,
+		"label":  rt.luaLabel,
+		"log":    rt.luaLog,
+		"now":    rt.luaNow,
+		"last":   rt.luaLast,
+	})
+	// 型を指定した引数: osc.float(1), osc.int64("9007199254740993") など
+	for _, typ := range []string{"int", "float", "string", "bool", "int64", "double", "blob", "timetag"} {
+		L.SetField(api, typ, L.NewFunction(func(L *lua.LState) int {
+			arg := L.NewTable()
+			arg.RawSetString("type", lua.LString(typ))
+			arg.RawSetString("value", lua.LString(lua.LVAsString(L.Get(1))))
+			L.Push(arg)
+			return 1
+		}))
+	}
+	L.SetGlobal("osc", api)
+	// printもログに出す
+	L.SetGlobal("print", L.NewFunction(rt.luaLog))
+}
+
+// luaSend osc.send(送信先, アドレス, 引数...) 送信先は送信先リストのnameか "host:port"
+// 送信できなければ false とエラーメッセージを返す
+func (rt *scriptRuntime) luaSend(L *lua.LState) int {
+	host, port, err := rt.script.engine.resolveDestination(L.CheckString(1))
+	if err != nil {
+		L.ArgError(1, err.Error())
+	}
+	return rt.send(L, host, port, L.CheckString(2), 3)
+}
+
+// luaReply osc.reply(受信したメッセージ, アドレス, 引数...) 受信したメッセージの送信元へ送る
+func (rt *scriptRuntime) luaReply(L *lua.LState) int {
+	source := lua.LVAsString(L.CheckTable(1).RawGetString("source"))
+	host, portText, err := net.SplitHostPort(source)
+	if err != nil {
+		L.ArgError(1, "unknown sender address")
+	}
+	port, _ := strconv.Atoi(portText)
+	return rt.send(L, host, port, L.CheckString(2), 3)
+}
+
+// send Luaの引数（start番目以降）を付けてメッセージを送る
+func (rt *scriptRuntime) send(L *lua.LState, host string, port int, address string, start int) int {
+	arguments, err := luaArguments(L, start)
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	msg, err := buildOSCMessage(address, arguments)
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+
+	key := net.JoinHostPort(host, strconv.Itoa(port))
+	client, ok := rt.clients[key]
+	if !ok {
+		client = osc.NewClient(host, port)
+		rt.clients[key] = client
+	}
+	if err := client.Send(msg); err != nil {
+		log.Printf("スクリプト %s の送信に失敗しました: %v", rt.script.config.Name, err)
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	rt.script.mu.Lock()
+	rt.script.sent++
+	rt.script.mu.Unlock()
+	rt.script.engine.sent(rt.script, fmt.Sprintf("%s %s [%s]", key, address, formatArguments(arguments)))
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// luaOn osc.on(パターン, 関数) 受信したメッセージのアドレスがパターンに一致したら関数を呼ぶ。IDを返す
+func (rt *scriptRuntime) luaOn(L *lua.LState) int {
+	pattern := L.CheckString(1)
+	fn := L.CheckFunction(2)
+	if _, err := compileOSCPattern(pattern); err != nil {
+		L.ArgError(1, err.Error())
+	}
+	id := rt.newID()
+	rt.script.mu.Lock()
+	rt.script.subs[id] = scriptSubscription{pattern: pattern, fn: fn}
+	rt.script.mu.Unlock()
+	L.Push(lua.LNumber(id))
+	return 1
+}
+
+// luaOff osc.off(ID) osc.on の登録をやめる
+func (rt *scriptRuntime) luaOff(L *lua.LState) int {
+	id := L.CheckInt(1)
+	rt.script.mu.Lock()
+	delete(rt.script.subs, id)
+	rt.script.mu.Unlock()
+	return 0
+}
+
+// luaTimer osc.after(ミリ秒, 関数) / osc.every(ミリ秒, 関数) IDを返す
+func (rt *scriptRuntime) luaTimer(L *lua.LState, repeat bool) int {
+	ms := L.CheckInt(1)
+	fn := L.CheckFunction(2)
+	if ms < 0 || (repeat && ms == 0) {
+		L.ArgError(1, fmt.Sprintf("invalid interval: %d", ms))
+	}
+
+	id := rt.newID()
+	fire := func(rt *scriptRuntime) error {
+		if _, ok := rt.timers[id]; !ok {
+			// 処理待ちの間に osc.cancel された
+			return nil
+		}
+		if !repeat {
+			delete(rt.timers, id)
+		}
+		return rt.call(fn)
+	}
+
+	interval := time.Duration(ms) * time.Millisecond
+	if repeat {
+		ticker := time.NewTicker(interval)
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-ticker.C:
+					rt.script.post(fire)
+				case <-done:
+					return
+				}
+			}
+		}()
+		rt.timers[id] = func() {
+			ticker.Stop()
+			close(done)
+		}
+	} else {
+		timer := time.AfterFunc(interval, func() { rt.script.post(fire) })
+		rt.timers[id] = func() { timer.Stop() }
+	}
+	L.Push(lua.LNumber(id))
+	return 1
+}
+
+// luaCancel osc.cancel(ID) タイマーを止める
+func (rt *scriptRuntime) luaCancel(L *lua.LState) int {
+	id := L.CheckInt(1)
+	if stopTimer, ok := rt.timers[id]; ok {
+		stopTimer()
+		delete(rt.timers, id)
+	}
+	return 0
+}
+
+// luaLabel osc.label(名前, 値) スクリプトのウィンドウに値を表示する
+func (rt *scriptRuntime) luaLabel(L *lua.LState) int {
+	name := L.CheckString(1)
+	text := L.ToStringMeta(L.CheckAny(2)).String()
+
+	s := rt.script
+	s.mu.Lock()
+	found := false
+	for i := range s.labels {
+		if s.labels[i].Name == name {
+			s.labels[i].Text = text
+			found = true
+			break
+		}
+	}
+	if !found {
+		s.labels = append(s.labels, ScriptLabel{Name: name, Text: text})
+	}
+	s.mu.Unlock()
+	s.engine.changed()
+	return 0
+}
+
+// luaLog osc.log(...) / print(...) ログに出力する
+func (rt *scriptRuntime) luaLog(L *lua.LState) int {
+	parts := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+	}
+	log.Printf("スクリプト %s: %s", rt.script.config.Name, strings.Join(parts, " "))
+	return 0
+}
+
+// luaNow osc.now() Unix時間（秒、小数つき）
+func (rt *scriptRuntime) luaNow(L *lua.LState) int {
+	L.Push(lua.LNumber(float64(time.Now().UnixNano()) / 1e9))
+	return 1
+}
+
+// luaLast osc.last(アドレス) そのアドレスで最後に受信した引数の配列（まだ受信していなければnil）
+func (rt *scriptRuntime) luaLast(L *lua.LState) int {
+	address := L.CheckString(1)
+	if rt.script.engine.last == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	values, ok := rt.script.engine.last.Get(address)
+	if !ok {
+		L.Push(lua.LNil)
+		return 1
+	}
+	args := L.NewTable()
+	for i, arg := range values {
+		args.RawSetInt(i+1, luaValue(arg))
+	}
+	L.Push(args)
+	return 1
+}
+
+// message 受信したメッセージをLuaのテーブルにする
+// {address=, args={...}, types="ifs", source="host:port", time=Unix時間}
+func (rt *scriptRuntime) message(msg OSCMessage) *lua.LTable {
+	t := rt.L.NewTable()
+	t.RawSetString("address", lua.LString(msg.Address))
+	t.RawSetString("source", lua.LString(msg.Source))
+	t.RawSetString("time", lua.LNumber(float64(msg.Time.UnixNano())/1e9))
+
+	args := rt.L.NewTable()
+	var types strings.Builder
+	for i, arg := range msg.Arguments {
+		args.RawSetInt(i+1, luaValue(arg))
+		types.WriteString(arg.TypeTag())
+	}
+	t.RawSetString("args", args)
+	t.RawSetString("types", lua.LString(types.String()))
+	return t
+}
+
+// luaValue 引数をLuaの値にする（数値の型は数値、boolは真偽値、nilはnil、それ以外は文字列）
+func luaValue(arg OSCArgument) lua.LValue {
+	switch arg.Type {
+	case "int", "float", "double", "int64", "timetag":
+		if n, err := strconv.ParseFloat(arg.Value, 64); err == nil {
+			return lua.LNumber(n)
+		}
+	case "bool":
+		return lua.LBool(arg.Value == "true")
+	case "nil":
+		return lua.LNil
+	}
+	return lua.LString(arg.Value)
+}
+
+// luaArguments Luaの引数（start番目以降）をOSCの引数にする
+// 整数はint、それ以外の数値はfloat。型を指定するときは osc.double(x) などを使う
+func luaArguments(L *lua.LState, start int) ([]OSCArgument, error) {
+	var arguments []OSCArgument
+	for i := start; i <= L.GetTop(); i++ {
+		var arg OSCArgument
+		switch v := L.Get(i).(type) {
+		case lua.LNumber:
+			n := float64(v)
+			if n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+				arg = OSCArgument{Type: "int", Value: strconv.FormatInt(int64(n), 10)}
+			} else {
+				arg = OSCArgument{Type: "float", Value: strconv.FormatFloat(n, 'g', -1, 64)}
+			}
+		case lua.LString:
+			arg = OSCArgument{Type: "string", Value: string(v)}
+		case lua.LBool:
+			arg = OSCArgument{Type: "bool", Value: strconv.FormatBool(bool(v))}
+		case *lua.LTable:
+			arg = OSCArgument{Type: lua.LVAsString(v.RawGetString("type")), Value: lua.LVAsString(v.RawGetString("value"))}
+			if _, err := arg.OSCValue(); err != nil {
+				return nil, fmt.Errorf("Arg%d: %w", i-start+1, err)
+			}
+		default:
+			if v == lua.LNil {
+				arg = OSCArgument{Type: "nil"}
+				break
+			}
+			return nil, fmt.Errorf("Arg%d: unsupported value: %s", i-start+1, v.Type())
+		}
+		arguments = append(arguments, arg)
+	}
+	return arguments, nil
+}
+
+// ScriptEngine 設定ファイルのスクリプトをまとめて管理する
+type ScriptEngine struct {
+	last *LastValues
+
+	// OnSend スクリプトがメッセージを送るたびに呼ばれる（スクリプトのゴルーチンから）
+	OnSend func(script, text string)
+
+	mu      sync.RWMutex
+	scripts []*Script
+	targets []SenderTarget
+
+	// version スクリプトの一覧・状態・表示が変わるたびに増える（表示の更新用）
+	version atomic.Uint64
+}
+
+// NewScriptEngine ScriptEngineを作成する。lastは osc.last で使う受信値
+func NewScriptEngine(last *LastValues) *ScriptEngine {
+	return &ScriptEngine{last: last}
+}
+
+// Load 実行中のスクリプトを止め、設定から読み込み直す。autostartのスクリプトは実行を始める
+// スクリプトファイルの場所は設定ファイルのディレクトリからの相対パス
+func (e *ScriptEngine) Load(configs []ScriptConfig, configFile string, targets []SenderTarget) error {
+	e.StopAll()
+
+	scripts := make([]*Script, 0, len(configs))
+	for _, cfg := range configs {
+		scripts = append(scripts, &Script{
+			config: cfg,
+			path:   scriptPath(configFile, cfg.File),
+			engine: e,
+			status: "Stopped",
+		})
+	}
+
+	e.mu.Lock()
+	e.scripts = scripts
+	e.mu.Unlock()
+	e.SetTargets(targets)
+	e.changed()
+
+	var errs []error
+	for _, script := range scripts {
+		if script.config.Autostart {
+			if err := script.Start(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetTargets osc.send で名前を指定したときに使う送信先リストを入れ替える（カードの編集に追従する）
+func (e *ScriptEngine) SetTargets(targets []SenderTarget) {
+	e.mu.Lock()
+	e.targets = append([]SenderTarget(nil), targets...)
+	e.mu.Unlock()
+}
+
+// ScriptsReferencing 送信先の名前を文字列として書いているスクリプトの名前を返す
+// スクリプトの中は書き換えられないので、送信先の名前を変える前の確認に使う
+func (e *ScriptEngine) ScriptsReferencing(name string) []string {
+	var names []string
+	for _, script := range e.Scripts() {
+		data, err := os.ReadFile(script.path)
+		if err != nil {
+			continue
+		}
+		source := string(data)
+		if strings.Contains(source, `"`+name+`"`) || strings.Contains(source, "'"+name+"'") {
+			names = append(names, script.config.Name)
+		}
+	}
+	return names
+}
+
+// Scripts スクリプトの一覧を返す
+func (e *ScriptEngine) Scripts() []*Script {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]*Script(nil), e.scripts...)
+}
+
+// StopAll すべてのスクリプトを止める
+func (e *ScriptEngine) StopAll() {
+	for _, script := range e.Scripts() {
+		script.Stop()
+	}
+}
+
+// Handle 受信したメッセージを実行中のスクリプトに渡す
+func (e *ScriptEngine) Handle(msg OSCMessage) {
+	for _, script := range e.Scripts() {
+		script.handle(msg)
+	}
+}
+
+// Version スクリプトの一覧・状態・表示が変わった回数
+func (e *ScriptEngine) Version() uint64 {
+	return e.version.Load()
+}
+
+// changed 表示の更新が必要なことを記録する
+func (e *ScriptEngine) changed() {
+	e.version.Add(1)
+}
+
+// sent 送信したことをOnSendに伝える
+func (e *ScriptEngine) sent(script *Script, text string) {
+	if e.OnSend != nil {
+		e.OnSend(script.config.Name, text)
+	}
+}
+
+// resolveDestination 送信先リストのnameまたは "host:port" を送信先にする
+func (e *ScriptEngine) resolveDestination(dest string) (string, int, error) {
+	if host, portText, err := net.SplitHostPort(dest); err == nil {
+		port, err := parsePort(portText)
+		if err != nil {
+			return "", 0, err
+		}
+		return host, port, nil
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, target := range e.targets {
+		if target.Name == dest {
+			return target.Host, target.Port, nil
+		}
+	}
+	return "", 0, fmt.Errorf("target not found: %s", dest)
+}
+
+// createScriptWindow スクリプトの実行ウィンドウを作成する
+func createScriptWindow(a fyne.App, engine *ScriptEngine) fyne.Window {
+	win := a.NewWindow("Scripts")
+
+	scriptsContainer := container.NewVBox()
+
+	// 一覧を作り直す関数
+	updateScriptsDisplay := func() {
+		scriptsContainer.RemoveAll()
+		scripts := engine.Scripts()
+		if len(scripts) == 0 {
+			scriptsContainer.Add(widget.NewLabel("No scripts. Add them under \"scripts:\" in the config file."))
+		}
+		for _, script := range scripts {
+			script := script
+			running, status, sent := script.Status()
+
+			statusLabel := widget.NewLabel(fmt.Sprintf("%s  (sent %d)", status, sent))
+			if strings.HasPrefix(status, "Error") {
+				statusLabel.Importance = widget.DangerImportance
+			} else if running {
+				statusLabel.Importance = widget.SuccessImportance
+			}
+			statusLabel.Wrapping = fyne.TextWrapWord
+
+			startStopBtn := widget.NewButton("Start", func() {
+				if err := script.Start(); err != nil {
+					dialog.ShowError(err, win)
+				}
+			})
+			restartBtn := widget.NewButton("Restart", func() {
+				// ファイルを読み込み直して最初から実行する
+				script.Stop()
+				if err := script.Start(); err != nil {
+					dialog.ShowError(err, win)
+				}
+			})
+			if running {
+				startStopBtn.SetText("Stop")
+				startStopBtn.OnTapped = func() {
+					script.Stop()
+				}
+			}
+
+			labelsBox := container.NewVBox()
+			for _, label := range script.Labels() {
+				labelsBox.Add(container.NewHBox(widget.NewLabelWithStyle(label.Name+":", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), widget.NewLabel(label.Text)))
+			}
+
+			cfg := script.Config()
+			scriptsContainer.Add(widget.NewCard(cfg.Name, script.Path(), container.NewVBox(
+				container.NewBorder(nil, nil, nil, container.NewHBox(startStopBtn, restartBtn), statusLabel),
+				labelsBox,
+			)))
+		}
+	}
+	updateScriptsDisplay()
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewCard("Scripts", "Lua scripts that send, receive and react to messages", nil),
+			container.NewHBox(widget.NewLabel("Scripts receive messages while the receiver is running"), layout.NewSpacer()),
+		),
+		nil, nil, nil,
+		container.NewScroll(scriptsContainer),
+	))
+	win.Resize(fyne.NewSize(600, 450))
+
+	// 閉じても再利用できるよう非表示にする（スクリプトは実行したまま）
+	win.SetCloseIntercept(func() {
+		win.Hide()
+	})
+
+	// 状態が変わったら表示を更新する
+	shownVersion := engine.Version()
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			fyne.Do(func() {
+				if v := engine.Version(); v != shownVersion {
+					shownVersion = v
+					updateScriptsDisplay()
+				}
+			})
+		}
+	}()
+
+	return win
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 送信側のシーケンス番号の付け方
+const (
+	sequenceOff      = "Seq: Off"
+	sequenceArgument = "Seq: Argument"
+	sequenceAddress  = "Seq: Address"
+)
+
+// sequenceModes 送信カードで選択できるシーケンス番号の付け方
+var sequenceModes = []string{sequenceOff, sequenceArgument, sequenceAddress}
+
+// sequenceAddressSuffix 専用アドレスで送る場合、カードのアドレスの後ろに付けるデフォルトのアドレス
+// （カードごとにアドレスが分かれるので、同じホストの複数のカードの番号が混ざらない）
+const sequenceAddressSuffix = "/seq"
+
+// defaultSequencePattern シーケンス監視の既定のパターン（どの階層の /seq にも一致する）
+const defaultSequencePattern = "//seq"
+
+// sequenceReorderWindow 番号がこれ以上戻った場合は送信側の再起動とみなす
+const sequenceReorderWindow = 64
+
+// maxSequenceRanges 送信元ごとに保持する欠落範囲の上限（超えた分は古い順に捨てる）
+const maxSequenceRanges = 1000
+
+// sequenceModeFromConfig SenderTarget.Sequence の値から選択肢と専用アドレスを決める
+// "argument" なら最後の引数、"/" で始まる値ならそのアドレスに番号を送る
+// 専用アドレスが空ならカードのアドレス + /seq に送る（sequenceAddressFor）
+func sequenceModeFromConfig(value string) (string, string) {
+	switch {
+	case value == "argument":
+		return sequenceArgument, ""
+	case value == "address":
+		return sequenceAddress, ""
+	case strings.HasPrefix(value, "/"):
+		return sequenceAddress, value
+	}
+	return sequenceOff, ""
+}
+
+// sequenceAddressFor 番号を送る専用アドレスを返す（configuredが空ならカードのアドレス + /seq）
+func sequenceAddressFor(configured, address string) string {
+	if configured != "" {
+		return configured
+	}
+	return strings.TrimSuffix(address, "/") + sequenceAddressSuffix
+}
+
+// sequenceConfigValue 選択中の付け方を SenderTarget.Sequence の値に戻す
+func sequenceConfigValue(mode, original string) string {
+	switch mode {
+	case sequenceArgument:
+		return "argument"
+	case sequenceAddress:
+		if strings.HasPrefix(original, "/") {
+			return original
+		}
+		return "address"
+	}
+	return ""
+}
+
+// SequenceRange 欠落しているシーケンス番号の範囲（両端を含む）
+type SequenceRange struct {
+	From int64
+	To   int64
+}
+
+// String 範囲を "15-20" または "15" の形式で返す
+func (r SequenceRange) String() string {
+	if r.From == r.To {
+		return strconv.FormatInt(r.From, 10)
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// SequenceStats 送信元IP・アドレスごとの集計
+type SequenceStats struct {
+	Source     string // "送信元IP アドレス"
+	Received   uint64 // 受信した番号の数（重複を除く）
+	Lost       uint64 // 現在欠落している番号の数
+	Duplicates uint64
+	OutOfOrder uint64 // 後から届いた番号の数
+	Restarts   uint64 // 番号が大きく戻った（送信側が再起動した）回数
+	First      int64
+	Last       int64 // これまでに受信した最大の番号
+	Missing    []SequenceRange
+}
+
+// LossPercent 最初から最大の番号までに対する欠落の割合
+func (s SequenceStats) LossPercent() float64 {
+	expected := s.Received + s.Lost
+	if expected == 0 {
+		return 0
+	}
+	return float64(s.Lost) / float64(expected) * 100
+}
+
+// addMissing 欠落範囲を番号順に追加する
+func (s *SequenceStats) addMissing(r SequenceRange) {
+	s.Lost += uint64(r.To - r.From + 1)
+	i := sort.Search(len(s.Missing), func(i int) bool { return s.Missing[i].From > r.From })
+	s.Missing = append(s.Missing, SequenceRange{})
+	copy(s.Missing[i+1:], s.Missing[i:])
+	s.Missing[i] = r
+	if len(s.Missing) > maxSequenceRanges {
+		s.Missing = s.Missing[len(s.Missing)-maxSequenceRanges:]
+	}
+}
+
+// removeMissing 後から届いた番号を欠落範囲から取り除く。欠落していなければfalse
+func (s *SequenceStats) removeMissing(n int64) bool {
+	i := sort.Search(len(s.Missing), func(i int) bool { return s.Missing[i].To >= n })
+	if i == len(s.Missing) || s.Missing[i].From > n {
+		return false
+	}
+
+	r := s.Missing[i]
+	switch {
+	case r.From == n && r.To == n:
+		s.Missing = append(s.Missing[:i], s.Missing[i+1:]...)
+	case r.From == n:
+		s.Missing[i].From = n + 1
+	case r.To == n:
+		s.Missing[i].To = n - 1
+	default:
+		// 範囲の途中なら2つに分ける
+		s.Missing = append(s.Missing, SequenceRange{})
+		copy(s.Missing[i+2:], s.Missing[i+1:])
+		s.Missing[i] = SequenceRange{From: r.From, To: n - 1}
+		s.Missing[i+1] = SequenceRange{From: n + 1, To: r.To}
+	}
+	s.Lost--
+	return true
+}
+
+// record シーケンス番号を1つ記録する
+func (s *SequenceStats) record(n int64) {
+	switch {
+	case n == s.Last+1:
+		s.Last = n
+		s.Received++
+	case n > s.Last+1:
+		s.addMissing(SequenceRange{From: s.Last + 1, To: n - 1})
+		s.Last = n
+		s.Received++
+	case s.removeMissing(n):
+		s.OutOfOrder++
+		s.Received++
+	case n < s.First && s.First-n <= sequenceReorderWindow:
+		// 最初に受信した番号より前の番号が遅れて届いた
+		if n+1 < s.First {
+			s.addMissing(SequenceRange{From: n + 1, To: s.First - 1})
+		}
+		s.First = n
+		s.OutOfOrder++
+		s.Received++
+	case s.Last-n > sequenceReorderWindow:
+		// 送信側が再起動したとみなして数え直す
+		*s = SequenceStats{Source: s.Source, Restarts: s.Restarts + 1, First: n, Last: n, Received: 1}
+	default:
+		s.Duplicates++
+	}
+}
+
+// SequenceTracker 受信メッセージのシーケンス番号から欠落・重複・順序の入れ替わりを検出する
+type SequenceTracker struct {
+	enabled atomic.Bool
+
+	mu       sync.Mutex
+	pattern  string
+	re       *regexp.Regexp
+	argument int // 番号が入っている引数（1始まり、0=最後の引数）
+	sources  map[string]*SequenceStats
+	order    []string
+}
+
+// NewSequenceTracker 無効状態のSequenceTrackerを作成する
+func NewSequenceTracker() *SequenceTracker {
+	return &SequenceTracker{sources: map[string]*SequenceStats{}}
+}
+
+// Configure 対象のアドレスパターン（空=すべて）と番号の引数位置を設定し、集計をリセットする
+func (t *SequenceTracker) Configure(pattern string, argument int) error {
+	if argument < 0 {
+		return fmt.Errorf("argument position must be 1 or more (0 = last): %d", argument)
+	}
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = compileOSCPattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pattern = pattern
+	t.re = re
+	t.argument = argument
+	t.sources = map[string]*SequenceStats{}
+	t.order = nil
+	return nil
+}
+
+// Enabled 集計中かどうか
+func (t *SequenceTracker) Enabled() bool {
+	return t.enabled.Load()
+}
+
+// SetEnabled 集計の開始・停止
+func (t *SequenceTracker) SetEnabled(enabled bool) {
+	t.enabled.Store(enabled)
+}
+
+// Reset 集計をリセットする
+func (t *SequenceTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sources = map[string]*SequenceStats{}
+	t.order = nil
+}
+
+// Handle 受信メッセージを集計する（受信ゴルーチンから呼ばれる）
+func (t *SequenceTracker) Handle(msg OSCMessage) {
+	if !t.enabled.Load() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.re != nil && !t.re.MatchString(msg.Address) {
+		return
+	}
+	n, ok := sequenceNumber(msg.Arguments, t.argument)
+	if !ok {
+		return
+	}
+
+	key := sequenceStreamKey(msg)
+	stats, found := t.sources[key]
+	if !found {
+		t.sources[key] = &SequenceStats{Source: key, First: n, Last: n, Received: 1}
+		t.order = append(t.order, key)
+		return
+	}
+	stats.record(n)
+}
+
+// sequenceStreamKey 集計の単位を "送信元IP アドレス" で返す
+// 送信側はメッセージごとに新しいUDPソケットを使うことがあり送信元ポートが毎回変わるため、ポートは含めない
+func sequenceStreamKey(msg OSCMessage) string {
+	host := msg.Source
+	if h, _, err := net.SplitHostPort(msg.Source); err == nil {
+		host = h
+	}
+	return host + " " + msg.Address
+}
+
+// Stats 送信元IP・アドレスごとの集計を最初に受信した順で返す
+func (t *SequenceTracker) Stats() []SequenceStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]SequenceStats, 0, len(t.order))
+	for _, source := range t.order {
+		stats := *t.sources[source]
+		stats.Missing = append([]SequenceRange(nil), stats.Missing...)
+		result = append(result, stats)
+	}
+	return result
+}
+
+// sequenceNumber 指定位置の整数引数を読み取る
+func sequenceNumber(arguments []OSCArgument, position int) (int64, bool) {
+	if len(arguments) == 0 {
+		return 0, false
+	}
+	index := len(arguments) - 1
+	if position > 0 {
+		index = position - 1
+	}
+	if index >= len(arguments) {
+		return 0, false
+	}
+	arg := arguments[index]
+	if arg.Type != "int" && arg.Type != "int64" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(arg.Value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// formatSequenceStats 集計を表示用の文字列にする
+func formatSequenceStats(stats []SequenceStats) string {
+	if len(stats) == 0 {
+		return "No sequence numbers received yet"
+	}
+
+	var b strings.Builder
+	for _, s := range stats {
+		fmt.Fprintf(&b, "%s  #%d-#%d\n", s.Source, s.First, s.Last)
+		fmt.Fprintf(&b, "  received %d / lost %d (%.2f%%) / duplicates %d / out-of-order %d / restarts %d\n",
+			s.Received, s.Lost, s.LossPercent(), s.Duplicates, s.OutOfOrder, s.Restarts)
+		if len(s.Missing) > 0 {
+			ranges := make([]string, 0, len(s.Missing))
+			for _, r := range s.Missing {
+				ranges = append(ranges, r.String())
+			}
+			fmt.Fprintf(&b, "  missing: %s\n", strings.Join(ranges, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// createSequenceWindow シーケンス番号の監視ウィンドウを作成
+func createSequenceWindow(a fyne.App, tracker *SequenceTracker) fyne.Window {
+	win := a.NewWindow("Sequence Monitor")
+
+	patternEntry := widget.NewEntry()
+	patternEntry.SetText(defaultSequencePattern)
+	patternEntry.SetPlaceHolder("//seq or /1/* (empty=all)")
+	argumentEntry := widget.NewEntry()
+	argumentEntry.SetPlaceHolder("empty=last argument")
+
+	statsLabel := widget.NewLabel(formatSequenceStats(nil))
+	statsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	statsLabel.Wrapping = fyne.TextWrapWord
+
+	var startBtn *widget.Button
+	startBtn = widget.NewButton("Start", func() {
+		if tracker.Enabled() {
+			tracker.SetEnabled(false)
+			startBtn.SetText("Start")
+			patternEntry.Enable()
+			argumentEntry.Enable()
+			return
+		}
+
+		argument := 0
+		if text := strings.TrimSpace(argumentEntry.Text); text != "" {
+			var err error
+			if argument, err = strconv.Atoi(text); err != nil || argument <= 0 {
+				dialog.ShowError(errors.New("argument position must be a whole number of 1 or more"), win)
+				return
+			}
+		}
+		if err := tracker.Configure(patternEntry.Text, argument); err != nil {
+			dialog.ShowError(err, win)
+			return
+		}
+		tracker.SetEnabled(true)
+		startBtn.SetText("Stop")
+		patternEntry.Disable()
+		argumentEntry.Disable()
+		statsLabel.SetText(formatSequenceStats(nil))
+	})
+
+	resetBtn := widget.NewButton("Reset", func() {
+		tracker.Reset()
+		statsLabel.SetText(formatSequenceStats(nil))
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Address", patternEntry),
+		widget.NewFormItem("Argument #", argumentEntry),
+	)
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewCard("Sequence Monitor", "Detect lost, duplicated and reordered messages", nil),
+			form,
+			container.NewHBox(startBtn, resetBtn, layout.NewSpacer()),
+			widget.NewSeparator(),
+		),
+		nil, nil, nil,
+		container.NewScroll(statsLabel),
+	))
+	win.Resize(fyne.NewSize(620, 480))
+
+	// 閉じても再利用できるよう非表示にする（集計は続ける）
+	win.SetCloseIntercept(func() {
+		win.Hide()
+	})
+
+	// 集計を定期的に更新する
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !tracker.Enabled() {
+				continue
+			}
+			text := formatSequenceStats(tracker.Stats())
+			fyne.Do(func() {
+				statsLabel.SetText(text)
+			})
+		}
+	}()
+
+	return win
+}
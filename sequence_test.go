@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSequenceStatsRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		numbers []int64
+		want    SequenceStats
+	}{
+		{"in order", []int64{0, 1, 2, 3},
+			SequenceStats{Received: 4, First: 0, Last: 3}},
+		{"gap", []int64{0, 1, 5},
+			SequenceStats{Received: 3, Lost: 3, First: 0, Last: 5, Missing: []SequenceRange{{2, 4}}}},
+		{"late arrival splits a gap", []int64{0, 5, 3},
+			SequenceStats{Received: 3, Lost: 3, OutOfOrder: 1, First: 0, Last: 5, Missing: []SequenceRange{{1, 2}, {4, 4}}}},
+		{"late arrivals at both ends of a gap", []int64{0, 4, 1, 3},
+			SequenceStats{Received: 4, Lost: 1, OutOfOrder: 2, First: 0, Last: 4, Missing: []SequenceRange{{2, 2}}}},
+		{"gap filled completely", []int64{0, 2, 1},
+			SequenceStats{Received: 3, OutOfOrder: 1, First: 0, Last: 2, Missing: []SequenceRange{}}},
+		{"duplicate", []int64{0, 1, 1, 0},
+			SequenceStats{Received: 2, Duplicates: 2, First: 0, Last: 1}},
+		{"before the first number", []int64{5, 6, 3},
+			SequenceStats{Received: 3, Lost: 1, OutOfOrder: 1, First: 3, Last: 6, Missing: []SequenceRange{{4, 4}}}},
+		{"restart", []int64{1000, 1001, 5, 6},
+			SequenceStats{Received: 2, Restarts: 1, First: 5, Last: 6}},
+		{"small jump back is a duplicate", []int64{0, 1, 2, 3, 1},
+			SequenceStats{Received: 4, Duplicates: 1, First: 0, Last: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := tt.numbers[0]
+			s := SequenceStats{First: n, Last: n, Received: 1}
+			for _, n := range tt.numbers[1:] {
+				s.record(n)
+			}
+			if !reflect.DeepEqual(s, tt.want) {
+				t.Errorf("stats = %+v, want %+v", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequenceStatsMissing(t *testing.T) {
+	var s SequenceStats
+	s.addMissing(SequenceRange{10, 12})
+	s.addMissing(SequenceRange{1, 3})
+	s.addMissing(SequenceRange{20, 20})
+	if want := []SequenceRange{{1, 3}, {10, 12}, {20, 20}}; !reflect.DeepEqual(s.Missing, want) || s.Lost != 7 {
+		t.Fatalf("Missing = %v, Lost = %d, want %v and 7 (sorted by number)", s.Missing, s.Lost, want)
+	}
+
+	tests := []struct {
+		n       int64
+		removed bool
+		want    []SequenceRange
+	}{
+		{11, true, []SequenceRange{{1, 3}, {10, 10}, {12, 12}, {20, 20}}}, // 範囲の途中
+		{1, true, []SequenceRange{{2, 3}, {10, 10}, {12, 12}, {20, 20}}},  // 範囲の先頭
+		{3, true, []SequenceRange{{2, 2}, {10, 10}, {12, 12}, {20, 20}}},  // 範囲の末尾
+		{20, true, []SequenceRange{{2, 2}, {10, 10}, {12, 12}}},           // 1つだけの範囲
+		{5, false, []SequenceRange{{2, 2}, {10, 10}, {12, 12}}},           // 欠落していない
+		{30, false, []SequenceRange{{2, 2}, {10, 10}, {12, 12}}},
+	}
+	lost := s.Lost
+	for _, tt := range tests {
+		if removed := s.removeMissing(tt.n); removed != tt.removed {
+			t.Errorf("removeMissing(%d) = %v, want %v", tt.n, removed, tt.removed)
+		}
+		if tt.removed {
+			lost--
+		}
+		if !reflect.DeepEqual(s.Missing, tt.want) || s.Lost != lost {
+			t.Errorf("after removeMissing(%d): Missing = %v, Lost = %d, want %v and %d", tt.n, s.Missing, s.Lost, tt.want, lost)
+		}
+	}
+}
+
+func TestSequenceStatsMissingLimit(t *testing.T) {
+	var s SequenceStats
+	for i := int64(0); i <= maxSequenceRanges; i++ {
+		s.addMissing(SequenceRange{i * 2, i * 2})
+	}
+	if len(s.Missing) != maxSequenceRanges || s.Missing[0].From != 2 {
+		t.Errorf("len(Missing) = %d, first = %v, want %d ranges without the oldest", len(s.Missing), s.Missing[0], maxSequenceRanges)
+	}
+}
+
+func TestSequenceTrackerStreams(t *testing.T) {
+	tracker := NewSequenceTracker()
+	if err := tracker.Configure(defaultSequencePattern, 0); err != nil {
+		t.Fatal(err)
+	}
+	tracker.SetEnabled(true)
+
+	// 同じホストの2枚のカードは、カードのアドレスごとの /seq に送るので別々に数える
+	cards := []string{"/1/fader1", "/1/fader2"}
+	for n := 0; n < 3; n++ {
+		for i, card := range cards {
+			tracker.Handle(OSCMessage{
+				Source:    "192.168.1.10:" + []string{"50000", "50001"}[i],
+				Address:   sequenceAddressFor("", card),
+				Arguments: []OSCArgument{{Type: "int", Value: strconv.Itoa(n)}},
+			})
+		}
+	}
+	stats := tracker.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("streams = %+v, want one per card", stats)
+	}
+	for _, s := range stats {
+		if s.Received != 3 || s.Duplicates != 0 || s.OutOfOrder != 0 || s.Lost != 0 {
+			t.Errorf("%s = %+v, want 3 in order", s.Source, s)
+		}
+	}
+	if stats[0].Source != "192.168.1.10 /1/fader1/seq" {
+		t.Errorf("Source = %q", stats[0].Source)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SessionRecord セッションファイルに記録する1メッセージ分のデータ
+// セッションファイルは1行1レコードのJSON Lines形式
+type SessionRecord struct {
+	Time      time.Time     `json:"time"`
+	Source    string        `json:"source,omitempty"`
+	Address   string        `json:"address"`
+	Arguments []OSCArgument `json:"arguments"`
+}
+
+// sessionRecordFromMessage 受信メッセージからセッションレコードを作成する
+func sessionRecordFromMessage(msg OSCMessage) SessionRecord {
+	return SessionRecord{
+		Time:      msg.Time,
+		Source:    msg.Source,
+		Address:   msg.Address,
+		Arguments: msg.Arguments,
+	}
+}
+
+// LoadSession セッションファイルを読み込む
+func LoadSession(filename string) ([]SessionRecord, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []SessionRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record SessionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// SessionWriter 受信メッセージをセッションファイルに追記する
+type SessionWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	count   int
+}
+
+// CreateSessionWriter セッションファイルを新規作成する
+func CreateSessionWriter(filename string) (*SessionWriter, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionWriter{
+		file:    f,
+		encoder: json.NewEncoder(f),
+	}, nil
+}
+
+// Write レコードを1件書き込む
+func (w *SessionWriter) Write(record SessionRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.encoder.Encode(record); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Count 書き込んだレコード数を返す
+func (w *SessionWriter) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Close ファイルを閉じる
+func (w *SessionWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
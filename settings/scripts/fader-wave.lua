@@ -0,0 +1,15 @@
+-- 4本のフェーダーを少しずつずらした波で10秒間動かす
+local step = 0
+local timer
+
+timer = osc.every(100, function()
+  step = step + 1
+  for ch = 1, 4 do
+    local value = (math.sin(step / 10 + ch) + 1) / 2
+    osc.send("Remote Device", "/1/fader" .. ch, osc.float(value))
+  end
+  osc.label("Step", step .. " / 100")
+  if step >= 100 then
+    osc.cancel(timer)
+  end
+end)
@@ -0,0 +1,9 @@
+-- /ping に /pong で応答する（最初の引数と応答した時刻を返す）
+local count = 0
+
+osc.on("/ping", function(msg)
+  count = count + 1
+  osc.reply(msg, "/pong", msg.args[1] or 0, osc.double(osc.now()))
+  osc.label("Pings", count)
+  osc.label("Last from", msg.source)
+end)
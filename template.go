@@ -0,0 +1,269 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	mathrand "math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypebeast/go-osc/osc"
+)
+
+// templatePattern 引数の値に書けるテンプレート {{name args...}}
+var templatePattern = regexp.MustCompile(`\{\{\s*([a-z_]+)((?:\s+[^\s{}]+)*)\s*\}\}`)
+
+// templateFunctions 使えるテンプレートと引数の数（最小・最大）
+var templateFunctions = map[string][2]int{
+	"counter": {0, 2}, // {{counter}}, {{counter 開始値}}, {{counter 開始値 増分}}
+	"now":     {0, 0}, // Unix時間（秒。float・doubleでは小数つき）
+	"now_ms":  {0, 0}, // Unix時間（ミリ秒）
+	"ntp":     {0, 0}, // NTP時刻（timetag）
+	"time":    {0, 1}, // 時刻の文字列（Goの書式、省略時は15:04:05.000）
+	"random":  {0, 2}, // {{random}}=0〜1, {{random 最大}}, {{random 最小 最大}}
+	"uuid":    {0, 0}, // ランダムなUUID（v4）
+	"last":    {1, 2}, // {{last /address}}, {{last /address 引数の番号}}（最後に受信した値）
+}
+
+// hasTemplate 値にテンプレートが含まれるかどうか
+func hasTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// checkTemplate テンプレートの書き方を検証する。typは引数タイプ（値が収まらないテンプレートを弾く）
+func checkTemplate(value, typ string) error {
+	matches := templatePattern.FindAllStringSubmatchIndex(value, -1)
+	rest := templatePattern.ReplaceAllString(value, "")
+	if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return fmt.Errorf("malformed template: %s", value)
+	}
+	for _, m := range matches {
+		name := value[m[2]:m[3]]
+		args := strings.Fields(value[m[4]:m[5]])
+		count, ok := templateFunctions[name]
+		if !ok {
+			return fmt.Errorf("unknown template: {{%s}}", name)
+		}
+		if len(args) < count[0] || len(args) > count[1] {
+			return fmt.Errorf("wrong number of arguments for {{%s}}", name)
+		}
+		if err := checkTemplateArgs(name, args); err != nil {
+			return err
+		}
+		// ミリ秒のUnix時間は32ビットに収まらない
+		if name == "now_ms" && typ == "int" {
+			return fmt.Errorf("{{now_ms}} does not fit in int; use int64")
+		}
+	}
+	return nil
+}
+
+// checkTemplateArgs テンプレートの引数を検証する
+func checkTemplateArgs(name string, args []string) error {
+	switch name {
+	case "counter", "random":
+		for _, arg := range args {
+			if _, err := strconv.ParseFloat(arg, 64); err != nil {
+				return fmt.Errorf("{{%s}} arguments must be numbers: %s", name, arg)
+			}
+		}
+	case "last":
+		if !strings.HasPrefix(args[0], "/") {
+			return fmt.Errorf("{{last}} address must start with /: %s", args[0])
+		}
+		if len(args) == 2 {
+			if n, err := strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("{{last}} argument number must be an integer of 1 or more: %s", args[1])
+			}
+		}
+	}
+	return nil
+}
+
+// LastValues 受信したアドレスごとの最後の引数（{{last}} テンプレート用）
+type LastValues struct {
+	mu     sync.RWMutex
+	values map[string][]OSCArgument
+}
+
+// NewLastValues LastValuesを作成する
+func NewLastValues() *LastValues {
+	return &LastValues{values: map[string][]OSCArgument{}}
+}
+
+// Handle 受信したメッセージの引数を記録する
+func (l *LastValues) Handle(msg OSCMessage) {
+	l.mu.Lock()
+	l.values[msg.Address] = msg.Arguments
+	l.mu.Unlock()
+}
+
+// Get アドレスに最後に受信した引数を返す
+func (l *LastValues) Get(address string) ([]OSCArgument, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	args, ok := l.values[address]
+	return args, ok
+}
+
+// ArgumentTemplates 送信時に引数のテンプレートを評価する（送信先カードごとにカウンターを持つ）
+type ArgumentTemplates struct {
+	last *LastValues
+
+	mu       sync.Mutex
+	counters map[string]float64 // キー: "引数の番号:テンプレートの番号"
+}
+
+// NewArgumentTemplates ArgumentTemplatesを作成する。lastはnilでもよい（{{last}} がエラーになる）
+func NewArgumentTemplates(last *LastValues) *ArgumentTemplates {
+	return &ArgumentTemplates{last: last, counters: map[string]float64{}}
+}
+
+// Reset カウンターを最初からにする
+func (t *ArgumentTemplates) Reset() {
+	t.mu.Lock()
+	t.counters = map[string]float64{}
+	t.mu.Unlock()
+}
+
+// Evaluate テンプレートを含む引数を送信時の値に置き換えた引数のリストを返す
+func (t *ArgumentTemplates) Evaluate(arguments []OSCArgument) ([]OSCArgument, error) {
+	result := make([]OSCArgument, len(arguments))
+	copy(result, arguments)
+	for i, arg := range arguments {
+		if !hasTemplate(arg.Value) {
+			continue
+		}
+		if err := checkTemplate(arg.Value, arg.Type); err != nil {
+			return nil, fmt.Errorf("Arg%d: %w", i+1, err)
+		}
+
+		var evalErr error
+		occurrence := 0
+		result[i].Value = templatePattern.ReplaceAllStringFunc(arg.Value, func(match string) string {
+			m := templatePattern.FindStringSubmatch(match)
+			value, err := t.evaluate(fmt.Sprintf("%d:%d", i, occurrence), m[1], strings.Fields(m[2]), arg.Type)
+			occurrence++
+			if err != nil && evalErr == nil {
+				evalErr = err
+			}
+			return value
+		})
+		if evalErr != nil {
+			return nil, fmt.Errorf("Arg%d: %w", i+1, evalErr)
+		}
+	}
+	return result, nil
+}
+
+// evaluate テンプレート1つを評価する（引数は検証済み）
+func (t *ArgumentTemplates) evaluate(key, name string, args []string, typ string) (string, error) {
+	numbers := make([]float64, 0, len(args))
+	if name == "counter" || name == "random" {
+		for _, arg := range args {
+			n, _ := strconv.ParseFloat(arg, 64)
+			numbers = append(numbers, n)
+		}
+	}
+	now := time.Now()
+
+	switch name {
+	case "counter":
+		start, step := 1.0, 1.0
+		if len(numbers) > 0 {
+			start = numbers[0]
+		}
+		if len(numbers) > 1 {
+			step = numbers[1]
+		}
+		t.mu.Lock()
+		value, ok := t.counters[key]
+		if !ok {
+			value = start
+		} else {
+			value += step
+		}
+		t.counters[key] = value
+		t.mu.Unlock()
+		return formatTemplateNumber(typ, value), nil
+
+	case "now":
+		if typ == "float" || typ == "double" {
+			return strconv.FormatFloat(float64(now.UnixNano())/1e9, 'f', 3, 64), nil
+		}
+		return strconv.FormatInt(now.Unix(), 10), nil
+
+	case "now_ms":
+		return strconv.FormatInt(now.UnixMilli(), 10), nil
+
+	case "ntp":
+		return strconv.FormatUint(osc.NewTimetag(now).TimeTag(), 10), nil
+
+	case "time":
+		layout := "15:04:05.000"
+		if len(args) > 0 {
+			layout = args[0]
+		}
+		return now.Format(layout), nil
+
+	case "random":
+		low, high := 0.0, 1.0
+		switch len(numbers) {
+		case 1:
+			high = numbers[0]
+		case 2:
+			low, high = numbers[0], numbers[1]
+		}
+		if high < low {
+			low, high = high, low
+		}
+		if typ == "int" || typ == "int64" {
+			// 整数は両端を含める
+			lo, hi := int64(math.Ceil(low)), int64(math.Floor(high))
+			if hi < lo {
+				return strconv.FormatInt(lo, 10), nil
+			}
+			return strconv.FormatInt(lo+mathrand.Int64N(hi-lo+1), 10), nil
+		}
+		return formatControlValue(typ, low+mathrand.Float64()*(high-low)), nil
+
+	case "uuid":
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", err
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+
+	case "last":
+		if t.last == nil {
+			return "", fmt.Errorf("{{last}} is only available while receiving")
+		}
+		values, ok := t.last.Get(args[0])
+		if !ok {
+			return "", fmt.Errorf("nothing received on %s yet", args[0])
+		}
+		index := 1
+		if len(args) > 1 {
+			index, _ = strconv.Atoi(args[1])
+		}
+		if index > len(values) {
+			return "", fmt.Errorf("%s has no argument %d (received %d)", args[0], index, len(values))
+		}
+		return values[index-1].Value, nil
+	}
+	return "", fmt.Errorf("unknown template: {{%s}}", name)
+}
+
+// formatTemplateNumber テンプレートの数値を引数タイプに合わせた文字列にする
+func formatTemplateNumber(typ string, value float64) string {
+	if typ == "float" || typ == "double" || value != math.Trunc(value) {
+		return formatControlValue(typ, value)
+	}
+	return strconv.FormatInt(int64(value), 10)
+}
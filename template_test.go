@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckTemplate(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr string // 空ならエラーなし
+	}{
+		{"plain text", ""},
+		{"{{counter}}", ""},
+		{"{{counter 100}}", ""},
+		{"{{counter 0 0.5}}", ""},
+		{"{{ counter  1 }}", ""},
+		{"cue-{{counter}}-{{uuid}}", ""},
+		{"{{now}}", ""},
+		{"{{time 15:04}}", ""},
+		{"{{random -1 1}}", ""},
+		{"{{last /fader1}}", ""},
+		{"{{last /fader1 2}}", ""},
+		{"{{count}}", "unknown template"},
+		{"{{Counter}}", "malformed template"}, // 名前は小文字のみ
+		{"{{now 1}}", "wrong number of arguments"},
+		{"{{counter 1 2 3}}", "wrong number of arguments"},
+		{"{{last}}", "wrong number of arguments"},
+		{"{{counter abc}}", "must be numbers"},
+		{"{{random 1 x}}", "must be numbers"},
+		{"{{last fader1}}", "must start with /"},
+		{"{{last /fader1 0}}", "integer of 1 or more"},
+		{"{{last /fader1 1.5}}", "integer of 1 or more"},
+		{"{{counter", "malformed template"},
+		{"counter}}", "malformed template"},
+		{"{{counter}} {{", "malformed template"},
+		{"{{}}", "malformed template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			err := checkTemplate(tt.value, "string")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkTemplate(%q) error = %v", tt.value, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkTemplate(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTemplateType(t *testing.T) {
+	tests := []struct {
+		value   string
+		typ     string
+		wantErr bool
+	}{
+		{"{{now_ms}}", "int64", false},
+		{"{{now_ms}}", "double", false},
+		{"{{now_ms}}", "string", false},
+		{"{{now_ms}}", "int", true}, // 32ビットに収まらない
+		{"{{now}}", "int", false},
+		{"{{counter}}", "int", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.typ, func(t *testing.T) {
+			if err := checkTemplate(tt.value, tt.typ); (err != nil) != tt.wantErr {
+				t.Errorf("checkTemplate(%q, %q) error = %v, wantErr %v", tt.value, tt.typ, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgumentTemplatesReset(t *testing.T) {
+	templates := NewArgumentTemplates(nil)
+	args := []OSCArgument{{Type: "int", Value: "{{counter 10}}"}}
+	for _, want := range []string{"10", "11"} {
+		result, err := templates.Evaluate(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result[0].Value != want {
+			t.Errorf("Value = %q, want %q", result[0].Value, want)
+		}
+	}
+	templates.Reset()
+	result, err := templates.Evaluate(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result[0].Value != "10" {
+		t.Errorf("Value = %q after Reset, want 10", result[0].Value)
+	}
+}
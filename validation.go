@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// argumentPatternCache コンパイル済みの文字列引数の正規表現
+var argumentPatternCache sync.Map
+
+// compileArgumentPattern 文字列引数の正規表現をコンパイルする（全体一致）
+func compileArgumentPattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := argumentPatternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %s", pattern)
+	}
+	argumentPatternCache.Store(pattern, re)
+	return re, nil
+}
+
+// validateRules 引数の入力ルールの設定を検証する（minとmaxは操作用ウィジェットの範囲にも使う）
+func (a SenderArgument) validateRules() error {
+	if a.Pattern != "" {
+		if _, err := compileArgumentPattern(a.Pattern); err != nil {
+			return err
+		}
+	}
+	if a.Max < a.Min {
+		return fmt.Errorf("max is less than min: %g < %g", a.Max, a.Min)
+	}
+	return nil
+}
+
+// isOmitted 省略可能な引数が空欄かどうか（空欄なら送信しない）
+func (a SenderArgument) isOmitted(arg OSCArgument) bool {
+	return a.Required != nil && !*a.Required && arg.Value == ""
+}
+
+// validateValue 入力された値を引数の設定に照らして検証する
+func (a SenderArgument) validateValue(arg OSCArgument) error {
+	if arg.Value == "" {
+		if a.Required != nil && *a.Required {
+			return errors.New("a value is required")
+		}
+		if a.isOmitted(arg) {
+			return nil
+		}
+	}
+
+	// 型として読み取れるか
+	if _, err := arg.OSCValue(); err != nil {
+		return err
+	}
+
+	if len(a.Allowed) > 0 {
+		found := false
+		for _, allowed := range a.Allowed {
+			if arg.Value == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("must be one of: %s", strings.Join(a.Allowed, ", "))
+		}
+	}
+
+	// 範囲はminとmaxの両方を指定した場合のみ確認する
+	if isNumericArgumentType(arg.Type) && a.Min != a.Max {
+		value, err := argumentNumber(arg)
+		if err != nil {
+			return err
+		}
+		if value < a.Min || value > a.Max {
+			return fmt.Errorf("must be between %g and %g", a.Min, a.Max)
+		}
+	}
+
+	if arg.Type == "string" && a.Pattern != "" {
+		re, err := compileArgumentPattern(a.Pattern)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(arg.Value) {
+			return fmt.Errorf("does not match the pattern %s", a.Pattern)
+		}
+	}
+	return nil
+}
+
+// prepareArguments すべての引数を検証し、省略された引数を除いた送信用の引数を返す
+func prepareArguments(definitions []SenderArgument, arguments []OSCArgument) ([]OSCArgument, error) {
+	result := make([]OSCArgument, 0, len(arguments))
+	for i, arg := range arguments {
+		var def SenderArgument
+		if i < len(definitions) {
+			def = definitions[i]
+		}
+		if err := def.validateValue(arg); err != nil {
+			return nil, fmt.Errorf("Arg%d: %w", i+1, err)
+		}
+		if def.isOmitted(arg) {
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result, nil
+}
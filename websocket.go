@@ -0,0 +1,711 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hypebeast/go-osc/osc"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket ブリッジの既定値
+const (
+	defaultWebSocketHost = "127.0.0.1"
+	defaultWebSocketPort = 8765
+	// bridgeClientBuffer クライアントごとに送信待ちにできるメッセージの数。あふれた分は捨てる
+	bridgeClientBuffer = 1024
+)
+
+// WebSocketSettings 受信メッセージをWebSocketで中継するブリッジの設定
+type WebSocketSettings struct {
+	Host      string `yaml:"host,omitempty"`      // 待ち受けるアドレス（既定 127.0.0.1、LANに公開するなら 0.0.0.0）
+	Port      int    `yaml:"port,omitempty"`      // 待ち受けるポート（既定 8765）
+	Autostart bool   `yaml:"autostart,omitempty"` // 起動時に開始する
+	// AllowedOrigins 接続を許可するブラウザのOrigin（例 http://localhost:3000、"*" ならすべて）
+	// Originを送らないブラウザ以外のクライアントは常に許可する
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	// AllowAnyDestination host・portの指定で送信先リストにない宛先へも送れるようにする
+	AllowAnyDestination bool `yaml:"allow_any_destination,omitempty"`
+}
+
+// Addr 待ち受けるアドレスを "host:port" で返す（省略された値は既定値）
+func (s WebSocketSettings) Addr() string {
+	host, port := s.Host, s.Port
+	if host == "" {
+		host = defaultWebSocketHost
+	}
+	if port == 0 {
+		port = defaultWebSocketPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// originAllowed Originの接続を許可するかどうか（Originがなければ許可）
+func (s WebSocketSettings) originAllowed(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range s.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// bridgeMessage クライアントに送る受信メッセージ（JSONエクスポートと同じ項目に "type": "message" を付ける）
+type bridgeMessage struct {
+	Type string `json:"type"`
+	ExportRecord
+}
+
+// bridgeRequest クライアントから受け取る要求
+// {"type": "send", "target": "..."} は送信先カードのSendボタンと同じ処理で送信する
+// host・port・address・argumentsを指定するとそのメッセージを送る（targetがあれば省略した値はtargetのもの）
+// {"type": "subscribe", "filter": "/1/*"} は受け取るアドレスを変える（空ならすべて）
+type bridgeRequest struct {
+	Type      string           `json:"type"`
+	ID        json.RawMessage  `json:"id,omitempty"` // 応答にそのまま付けて返す
+	Target    string           `json:"target,omitempty"`
+	Host      string           `json:"host,omitempty"`
+	Port      int              `json:"port,omitempty"`
+	Address   string           `json:"address,omitempty"`
+	Arguments []bridgeArgument `json:"arguments,omitempty"`
+	Filter    string           `json:"filter,omitempty"`
+}
+
+// bridgeArgument 要求の引数。valueは文字列のほかJSONの数値・真偽値・nullも受け付ける
+// typeを省略したら値から決める（整数=int、小数=float、真偽値=bool、null=nil、文字列=string）
+type bridgeArgument struct {
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// bridgeResponse 要求への応答
+type bridgeResponse struct {
+	Type   string          `json:"type"` // "sent", "subscribed", "error"
+	ID     json.RawMessage `json:"id,omitempty"`
+	Filter *string         `json:"filter,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// OSCArgument 要求の引数をOSCArgumentにする
+func (a bridgeArgument) OSCArgument() (OSCArgument, error) {
+	raw := bytes.TrimSpace(a.Value)
+	var value string
+	kind := ""
+	switch {
+	case len(raw) == 0 || string(raw) == "null":
+		kind = "nil"
+	case raw[0] == '"':
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return OSCArgument{}, err
+		}
+		kind = "string"
+	case string(raw) == "true" || string(raw) == "false":
+		value, kind = string(raw), "bool"
+	default:
+		var n json.Number
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return OSCArgument{}, fmt.Errorf("cannot read value: %s", raw)
+		}
+		value, kind = n.String(), "float"
+		if _, err := strconv.ParseInt(value, 10, 32); err == nil {
+			kind = "int"
+		}
+	}
+
+	arg := OSCArgument{Type: a.Type, Value: value}
+	if arg.Type == "" {
+		arg.Type = kind
+	}
+	if arg.TypeTag() == "?" {
+		return arg, fmt.Errorf("unsupported argument type: %s", arg.Type)
+	}
+	return arg, nil
+}
+
+// bridgeClient 接続中のクライアント
+type bridgeClient struct {
+	conn      *websocket.Conn
+	remote    string
+	connected time.Time
+	out       chan []byte
+	done      chan struct{} // 接続の処理が終わったら閉じる
+	closed    chan struct{} // writeLoopが終わったら閉じる（送信に失敗したときも）
+
+	mu     sync.Mutex
+	filter string
+
+	pushed  atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// Filter 受け取るアドレスのパターン（空ならすべて）
+func (c *bridgeClient) Filter() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.filter
+}
+
+// setFilter 受け取るアドレスのパターンを変える
+func (c *bridgeClient) setFilter(filter string) error {
+	if filter != "" {
+		if _, err := compileOSCPattern(filter); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.filter = filter
+	c.mu.Unlock()
+	return nil
+}
+
+// push 送信待ちに追加する。waitがfalseなら、あふれたときは捨てる
+func (c *bridgeClient) push(data []byte, wait bool) {
+	if wait {
+		select {
+		case c.out <- data:
+		case <-c.done:
+		case <-c.closed:
+		}
+		return
+	}
+	select {
+	case c.out <- data:
+		c.pushed.Add(1)
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// writeLoop 送信待ちのメッセージを順番にテキストフレームで送る
+// 送信に失敗したら接続を閉じ、closedで待っているpushを止める
+func (c *bridgeClient) writeLoop() {
+	defer close(c.closed)
+	for {
+		select {
+		case data := <-c.out:
+			if err := websocket.Message.Send(c.conn, string(data)); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-c.done:
+			// 切断前に積んだ応答（filterのエラーなど）は送ってから終わる
+			for {
+				select {
+				case data := <-c.out:
+					if websocket.Message.Send(c.conn, string(data)) != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// BridgeClientInfo 接続中のクライアントの表示用の情報
+type BridgeClientInfo struct {
+	Remote    string
+	Filter    string
+	Connected time.Time
+	Pushed    uint64
+	Dropped   uint64
+}
+
+// WebSocketBridge 受信したメッセージをWebSocketのクライアントにJSONで送り、
+// クライアントからのJSONをOSCで送信する
+type WebSocketBridge struct {
+	// SendTarget 送信先カードの内容をSendボタンと同じ処理で送る（クライアントのゴルーチンから呼ばれる）
+	SendTarget func(name string) error
+	// Targets 送信先リストを返す（クライアントのゴルーチンから呼ばれる）
+	Targets func() []SenderTarget
+	// OnSend メッセージを送るたびに呼ばれる（送信履歴用）
+	OnSend func(text string)
+
+	templates *ArgumentTemplates
+
+	mu       sync.Mutex
+	server   *http.Server
+	addr     string
+	settings WebSocketSettings // 許可するOriginと送信先（SetSettingsで変わる）
+	clients  map[*bridgeClient]bool
+	count    atomic.Int32 // 接続数（受信のたびにロックしないため）
+
+	// version 開始・停止・接続・切断のたびに増える（表示の更新用）
+	version atomic.Uint64
+}
+
+// NewWebSocketBridge WebSocketBridgeを作成する。lastは引数の {{last}} テンプレートで使う受信値
+func NewWebSocketBridge(last *LastValues) *WebSocketBridge {
+	return &WebSocketBridge{
+		templates: NewArgumentTemplates(last),
+		clients:   map[*bridgeClient]bool{},
+	}
+}
+
+// SetSettings 許可するOriginと送信先の設定を変える（待ち受け中でも次の接続・送信から反映する）
+func (b *WebSocketBridge) SetSettings(settings WebSocketSettings) {
+	b.mu.Lock()
+	b.settings = settings
+	b.mu.Unlock()
+}
+
+// Settings 現在の設定を返す
+func (b *WebSocketBridge) Settings() WebSocketSettings {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.settings
+}
+
+// handshake ブラウザからの接続はallowed_originsにあるOriginだけ受け付ける
+// （他のWebページからlocalhostのブリッジを操作されないようにする）
+func (b *WebSocketBridge) handshake(_ *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if !b.Settings().originAllowed(origin) {
+		log.Printf("WebSocket接続を拒否しました（allowed_origins にないOrigin）: %s %s", req.RemoteAddr, origin)
+		return fmt.Errorf("origin not allowed: %s", origin)
+	}
+	return nil
+}
+
+// Start addr（host:port）で待ち受けを始める
+func (b *WebSocketBridge) Start(addr string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.server != nil {
+		return errors.New("the WebSocket bridge is already running")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot start the WebSocket bridge: %w", err)
+	}
+	handler := websocket.Server{
+		Handshake: b.handshake,
+		Handler:   b.serve,
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("WebSocketブリッジエラー: %v", err)
+		}
+	}()
+
+	b.server, b.addr = server, listener.Addr().String()
+	b.version.Add(1)
+	log.Printf("WebSocketブリッジを開始しました: ws://%s/", b.addr)
+	return nil
+}
+
+// Stop 待ち受けをやめ、すべてのクライアントを切断する
+func (b *WebSocketBridge) Stop() {
+	b.mu.Lock()
+	server := b.server
+	b.server = nil
+	clients := make([]*bridgeClient, 0, len(b.clients))
+	for c := range b.clients {
+		clients = append(clients, c)
+	}
+	b.mu.Unlock()
+	if server == nil {
+		return
+	}
+
+	server.Close()
+	// Upgradeした接続はhttp.Serverでは閉じられない
+	for _, c := range clients {
+		c.conn.Close()
+	}
+	b.version.Add(1)
+	log.Printf("WebSocketブリッジを停止しました")
+}
+
+// Running 待ち受け中かどうかと、待ち受けているアドレスを返す
+func (b *WebSocketBridge) Running() (bool, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.server != nil, b.addr
+}
+
+// Clients 接続中のクライアントの一覧を接続した順に返す
+func (b *WebSocketBridge) Clients() []BridgeClientInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	infos := make([]BridgeClientInfo, 0, len(b.clients))
+	for c := range b.clients {
+		infos = append(infos, BridgeClientInfo{
+			Remote:    c.remote,
+			Filter:    c.Filter(),
+			Connected: c.connected,
+			Pushed:    c.pushed.Load(),
+			Dropped:   c.dropped.Load(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Connected.Equal(infos[j].Connected) {
+			return infos[i].Connected.Before(infos[j].Connected)
+		}
+		return infos[i].Remote < infos[j].Remote
+	})
+	return infos
+}
+
+// Version 開始・停止・接続・切断の回数
+func (b *WebSocketBridge) Version() uint64 {
+	return b.version.Load()
+}
+
+// Handle 受信したメッセージを、フィルタに一致するクライアントに送る
+func (b *WebSocketBridge) Handle(msg OSCMessage) {
+	if b.count.Load() == 0 {
+		return
+	}
+
+	arguments := msg.Arguments
+	if arguments == nil {
+		arguments = []OSCArgument{}
+	}
+	data, err := json.Marshal(bridgeMessage{Type: "message", ExportRecord: ExportRecord{
+		Time:      formatExportTime(msg.Time),
+		Source:    msg.Source,
+		Address:   msg.Address,
+		TypeTags:  typeTagsOf(msg.Arguments),
+		Arguments: arguments,
+	}})
+	if err != nil {
+		log.Printf("WebSocketブリッジ: %v", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		if filter := c.Filter(); filter == "" || matchOSCPattern(filter, msg.Address) {
+			c.push(data, false)
+		}
+	}
+}
+
+// serve 1つのクライアントとの接続を処理する
+// 接続時のURLの ?filter=/1/* で受け取るアドレスを指定できる
+func (b *WebSocketBridge) serve(conn *websocket.Conn) {
+	c := &bridgeClient{
+		conn:      conn,
+		remote:    conn.Request().RemoteAddr,
+		connected: time.Now(),
+		out:       make(chan []byte, bridgeClientBuffer),
+		done:      make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+	go c.writeLoop()
+	defer close(c.done)
+
+	if err := c.setFilter(conn.Request().URL.Query().Get("filter")); err != nil {
+		c.push(b.response(bridgeResponse{Type: "error", Error: err.Error()}), true)
+		return
+	}
+
+	b.mu.Lock()
+	if b.server == nil {
+		b.mu.Unlock()
+		return
+	}
+	b.clients[c] = true
+	b.count.Add(1)
+	b.mu.Unlock()
+	b.version.Add(1)
+	log.Printf("WebSocketクライアントが接続しました: %s", c.remote)
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.count.Add(-1)
+		b.mu.Unlock()
+		b.version.Add(1)
+		log.Printf("WebSocketクライアントが切断しました: %s", c.remote)
+	}()
+
+	for {
+		var text string
+		if err := websocket.Message.Receive(conn, &text); err != nil {
+			return
+		}
+		c.push(b.handleRequest(c, []byte(text)), true)
+	}
+}
+
+// response 応答をJSONにする
+func (b *WebSocketBridge) response(resp bridgeResponse) []byte {
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+// handleRequest クライアントからの要求を処理して応答を返す
+func (b *WebSocketBridge) handleRequest(c *bridgeClient, data []byte) []byte {
+	var req bridgeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return b.response(bridgeResponse{Type: "error", Error: fmt.Sprintf("invalid JSON: %v", err)})
+	}
+
+	switch req.Type {
+	case "subscribe":
+		if err := c.setFilter(req.Filter); err != nil {
+			return b.response(bridgeResponse{Type: "error", ID: req.ID, Error: err.Error()})
+		}
+		filter := req.Filter
+		b.version.Add(1)
+		return b.response(bridgeResponse{Type: "subscribed", ID: req.ID, Filter: &filter})
+	case "send":
+		if err := b.send(c, req); err != nil {
+			return b.response(bridgeResponse{Type: "error", ID: req.ID, Error: err.Error()})
+		}
+		return b.response(bridgeResponse{Type: "sent", ID: req.ID})
+	}
+	return b.response(bridgeResponse{Type: "error", ID: req.ID, Error: fmt.Sprintf("unknown request type: %q (send, subscribe)", req.Type)})
+}
+
+// send 要求のメッセージを送信する
+func (b *WebSocketBridge) send(c *bridgeClient, req bridgeRequest) error {
+	// 送信先だけなら送信先カードのSendボタンと同じ処理で送る
+	if req.Target != "" && req.Host == "" && req.Port == 0 && req.Address == "" && req.Arguments == nil {
+		if b.SendTarget == nil {
+			return errors.New("sending from target cards is not available")
+		}
+		return b.SendTarget(req.Target)
+	}
+
+	var targets []SenderTarget
+	if b.Targets != nil {
+		targets = b.Targets()
+	}
+	host, port, address := req.Host, req.Port, req.Address
+	// definitions 送信先カードのアドレスに送るときは、カードの引数の設定で検証する
+	var definitions []SenderArgument
+	if req.Target != "" {
+		target, ok := findSenderTarget(targets, req.Target)
+		if !ok {
+			return fmt.Errorf("target not found: %s", req.Target)
+		}
+		if host == "" {
+			host = target.Host
+		}
+		if port == 0 {
+			port = target.Port
+		}
+		if address == "" {
+			address = target.Address
+		}
+		if address == target.Address {
+			definitions = target.Arguments
+		}
+	}
+	if host == "" {
+		return errors.New("target or host is required")
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid port number: %d", port)
+	}
+	if !strings.HasPrefix(address, "/") {
+		return fmt.Errorf("OSC address must start with /: %q", address)
+	}
+	// host・portの指定は、allow_any_destinationでなければ送信先リストにある宛先だけ
+	if (req.Host != "" || req.Port != 0) && !b.Settings().AllowAnyDestination && !hasDestination(targets, host, port) {
+		return fmt.Errorf("destination is not in the target list: %s (set allow_any_destination to allow it)", net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+
+	arguments := make([]OSCArgument, 0, len(req.Arguments))
+	for i, a := range req.Arguments {
+		arg, err := a.OSCArgument()
+		if err != nil {
+			return fmt.Errorf("Arg%d: %w", i+1, err)
+		}
+		arguments = append(arguments, arg)
+	}
+	// 送信先カードのSendボタンと同じく、テンプレートを評価してから引数を検証する
+	arguments, err := b.templates.Evaluate(arguments)
+	if err != nil {
+		return err
+	}
+	arguments, err = prepareArguments(definitions, arguments)
+	if err != nil {
+		return err
+	}
+	msg, err := buildOSCMessage(address, arguments)
+	if err != nil {
+		return err
+	}
+
+	// go-oscのClientは送信のたびに接続するので、宛先ごとに作っておく必要はない
+	if err := osc.NewClient(host, port).Send(msg); err != nil {
+		return err
+	}
+
+	if b.OnSend != nil {
+		b.OnSend(fmt.Sprintf("WebSocket %s: %s %s [%s]", c.remote, net.JoinHostPort(host, strconv.Itoa(port)), address, formatArguments(arguments)))
+	}
+	return nil
+}
+
+// hasDestination 送信先リストに host:port の送信先があるかどうか
+// ホスト名は名前解決したアドレスで比べる（localhost と 127.0.0.1 は同じ宛先）
+func hasDestination(targets []SenderTarget, host string, port int) bool {
+	var addr *net.UDPAddr
+	for _, target := range targets {
+		if target.Port != port {
+			continue
+		}
+		if strings.EqualFold(target.Host, host) {
+			return true
+		}
+		if addr == nil {
+			resolved, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err != nil {
+				return false
+			}
+			addr = resolved
+		}
+		if resolved, err := net.ResolveUDPAddr("udp", net.JoinHostPort(target.Host, strconv.Itoa(target.Port))); err == nil && resolved.IP.Equal(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// createWebSocketWindow WebSocketブリッジの設定・状態ウィンドウを作成する
+// settingsは開始するときのアドレスの初期値を返す
+func createWebSocketWindow(a fyne.App, bridge *WebSocketBridge, settings func() WebSocketSettings) fyne.Window {
+	win := a.NewWindow("WebSocket Bridge")
+
+	addrEntry := widget.NewEntry()
+	addrEntry.SetText(settings().Addr())
+	addrEntry.SetPlaceHolder("127.0.0.1:8765")
+
+	statusLabel := widget.NewLabel("")
+	clientsContainer := container.NewVBox()
+
+	var startStopBtn *widget.Button
+	startStopBtn = widget.NewButton("Start", func() {
+		if running, _ := bridge.Running(); running {
+			bridge.Stop()
+			return
+		}
+		if _, _, err := net.SplitHostPort(addrEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("enter the address as host:port: %s", addrEntry.Text), win)
+			return
+		}
+		if err := bridge.Start(addrEntry.Text); err != nil {
+			dialog.ShowError(err, win)
+		}
+	})
+
+	// クライアント1件の表示
+	clientText := func(client BridgeClientInfo) string {
+		filter := client.Filter
+		if filter == "" {
+			filter = "(all)"
+		}
+		return fmt.Sprintf("%s  filter %s  pushed %d  dropped %d  since %s",
+			client.Remote, filter, client.Pushed, client.Dropped, client.Connected.Format("15:04:05"))
+	}
+
+	// 状態と接続中のクライアントを表示する関数
+	var clientLabels []*widget.Label
+	updateDisplay := func() {
+		running, addr := bridge.Running()
+		if running {
+			startStopBtn.SetText("Stop")
+			addrEntry.Disable()
+			statusLabel.SetText(fmt.Sprintf("● Listening on ws://%s/", addr))
+			statusLabel.Importance = widget.SuccessImportance
+		} else {
+			startStopBtn.SetText("Start")
+			addrEntry.Enable()
+			statusLabel.SetText("Stopped")
+			statusLabel.Importance = widget.MediumImportance
+		}
+		statusLabel.Refresh()
+
+		clientsContainer.RemoveAll()
+		clientLabels = nil
+		clients := bridge.Clients()
+		if len(clients) == 0 {
+			clientsContainer.Add(widget.NewLabel("No clients connected"))
+		}
+		for _, client := range clients {
+			label := widget.NewLabel(clientText(client))
+			clientLabels = append(clientLabels, label)
+			clientsContainer.Add(label)
+		}
+	}
+	updateDisplay()
+
+	// 送信数などのカウンタだけを更新する関数（一覧は作り直さない）
+	updateCounters := func() {
+		clients := bridge.Clients()
+		if len(clients) != len(clientLabels) {
+			return
+		}
+		for i, client := range clients {
+			if text := clientText(client); clientLabels[i].Text != text {
+				clientLabels[i].SetText(text)
+			}
+		}
+	}
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewCard("WebSocket Bridge", "Push received messages to web clients as JSON and send their JSON messages as OSC", nil),
+			container.NewBorder(nil, nil, widget.NewLabel("Address:"), startStopBtn, addrEntry),
+			statusLabel,
+			widget.NewLabel("Messages are pushed while the receiver is running"),
+			widget.NewSeparator(),
+			widget.NewLabel("Clients"),
+		),
+		nil, nil, nil,
+		container.NewScroll(clientsContainer),
+	))
+	win.Resize(fyne.NewSize(620, 400))
+
+	// 閉じても再利用できるよう非表示にする（ブリッジは動かしたまま）
+	win.SetCloseIntercept(func() {
+		win.Hide()
+	})
+
+	// 状態が変わったら表示を作り直し、変わっていなければカウンタだけ更新する
+	shownVersion := bridge.Version()
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for range ticker.C {
+			fyne.Do(func() {
+				if v := bridge.Version(); v != shownVersion {
+					shownVersion = v
+					updateDisplay()
+				} else {
+					updateCounters()
+				}
+			})
+		}
+	}()
+
+	return win
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBridgeArgumentOSCArgument(t *testing.T) {
+	tests := []struct {
+		typ     string
+		value   string // JSONの値（空なら省略）
+		want    OSCArgument
+		wantErr bool
+	}{
+		{"", `1`, OSCArgument{Type: "int", Value: "1"}, false},
+		{"", `-42`, OSCArgument{Type: "int", Value: "-42"}, false},
+		{"", `0.5`, OSCArgument{Type: "float", Value: "0.5"}, false},
+		{"", `1e3`, OSCArgument{Type: "float", Value: "1e3"}, false},
+		{"", `3000000000`, OSCArgument{Type: "float", Value: "3000000000"}, false}, // int32に収まらない
+		{"", `true`, OSCArgument{Type: "bool", Value: "true"}, false},
+		{"", `false`, OSCArgument{Type: "bool", Value: "false"}, false},
+		{"", `null`, OSCArgument{Type: "nil"}, false},
+		{"", ``, OSCArgument{Type: "nil"}, false},
+		{"", `"hello"`, OSCArgument{Type: "string", Value: "hello"}, false},
+		{"", `"{{counter}}"`, OSCArgument{Type: "string", Value: "{{counter}}"}, false},
+		{"int64", `3000000000`, OSCArgument{Type: "int64", Value: "3000000000"}, false},
+		{"double", `1`, OSCArgument{Type: "double", Value: "1"}, false},
+		{"int", `"7"`, OSCArgument{Type: "int", Value: "7"}, false}, // 文字列でもtypeに従う
+		{"", `[1]`, OSCArgument{}, true},
+		{"", `{"a": 1}`, OSCArgument{}, true},
+		{"decimal", `1`, OSCArgument{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ+" "+tt.value, func(t *testing.T) {
+			arg := bridgeArgument{Type: tt.typ, Value: json.RawMessage(tt.value)}
+			got, err := arg.OSCArgument()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OSCArgument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("OSCArgument() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketSettingsOriginAllowed(t *testing.T) {
+	tests := []struct {
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{nil, "", true}, // Originを送らないクライアント
+		{nil, "http://localhost:3000", false},
+		{[]string{"http://localhost:3000"}, "http://localhost:3000", true},
+		{[]string{"http://localhost:3000/"}, "http://localhost:3000", true},
+		{[]string{"http://localhost:3000"}, "http://LOCALHOST:3000/", true},
+		{[]string{"http://localhost:3000"}, "http://localhost:3001", false},
+		{[]string{"http://localhost:3000"}, "https://localhost:3000", false},
+		{[]string{"http://localhost:3000"}, "http://evil.example", false},
+		{[]string{"*"}, "http://evil.example", true},
+	}
+	for _, tt := range tests {
+		settings := WebSocketSettings{AllowedOrigins: tt.allowed}
+		if got := settings.originAllowed(tt.origin); got != tt.want {
+			t.Errorf("originAllowed(%q) with %v = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestHasDestination(t *testing.T) {
+	targets := []SenderTarget{
+		{Name: "Local", Host: "localhost", Port: 9000},
+		{Name: "Mixer", Host: "192.0.2.10", Port: 8000},
+	}
+	tests := []struct {
+		host string
+		port int
+		want bool
+	}{
+		{"localhost", 9000, true},
+		{"LOCALHOST", 9000, true},
+		{"127.0.0.1", 9000, true}, // 名前解決したアドレスで比べる
+		{"127.0.0.1", 9001, false},
+		{"192.0.2.10", 8000, true},
+		{"192.0.2.11", 8000, false},
+		{"192.0.2.10", 9000, false},
+	}
+	for _, tt := range tests {
+		if got := hasDestination(targets, tt.host, tt.port); got != tt.want {
+			t.Errorf("hasDestination(%s, %d) = %v, want %v", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestWebSocketBridgeSendValidatesLikeCard(t *testing.T) {
+	bridge := NewWebSocketBridge(nil)
+	bridge.Targets = func() []SenderTarget {
+		return []SenderTarget{{
+			Name: "Fader", Host: "127.0.0.1", Port: 9000, Address: "/fader",
+			Arguments: []SenderArgument{{Type: "float", Min: 0, Max: 1}},
+		}}
+	}
+	client := &bridgeClient{remote: "test"}
+	tests := []struct {
+		name string
+		req  string
+	}{
+		{"out of range", `{"type":"send","target":"Fader","arguments":[{"value":2.5}]}`},
+		{"not a number", `{"type":"send","target":"Fader","arguments":[{"type":"float","value":"loud"}]}`},
+		{"bad address", `{"type":"send","target":"Fader","address":"fader"}`},
+		{"unknown host", `{"type":"send","host":"192.0.2.1","port":9000,"address":"/x"}`},
+		{"unknown target", `{"type":"send","target":"Lights","address":"/x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req bridgeRequest
+			if err := json.Unmarshal([]byte(tt.req), &req); err != nil {
+				t.Fatal(err)
+			}
+			if err := bridge.send(client, req); err == nil {
+				t.Errorf("send(%s) error = nil", tt.req)
+			}
+		})
+	}
+}